@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/audit"
+	"members/bot"
+	"members/retention"
+	"members/tokens"
+)
+
+// accountDeleteTTL is how long the confirmation link stays valid.
+const accountDeleteTTL = time.Hour
+
+type accountDeleteConfirmRequest struct {
+	Token string `json:"token"`
+}
+
+// RequestAccountDeletionHandler emails the authenticated user a link to
+// confirm the deletion of their account.
+func RequestAccountDeletionHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		tokenRecord, err := tokens.Issue(app, authRecord.Id, tokens.ServiceAccountDelete, accountDeleteTTL, tokens.IssueOptions{})
+		if err != nil {
+			return apis.NewBadRequestError("Failed to generate token", err)
+		}
+
+		link := strings.TrimSuffix(appURL(app), "/") + "/#/account-delete?token=" + tokenRecord.GetString("token")
+
+		message := &mailer.Message{
+			From: mail.Address{
+				Address: app.Settings().Meta.SenderAddress,
+				Name:    app.Settings().Meta.SenderName,
+			},
+			To:      []mail.Address{{Address: authRecord.GetString("email")}},
+			Subject: "Confirm the deletion of your account",
+			Text: fmt.Sprintf(
+				"We received a request to delete your account.\n\n"+
+					"Open this link within one hour to confirm:\n%s\n\n"+
+					"Your account, your group memberships and your Telegram access will be removed. "+
+					"If you did not ask for this, ignore this email.",
+				link,
+			),
+		}
+
+		if err := app.NewMailClient().Send(message); err != nil {
+			return apis.NewBadRequestError("Failed to send confirmation email", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"sent": true,
+		})
+	}
+}
+
+// ConfirmAccountDeletionHandler deletes the account of the token owner.
+// The token from the confirmation email is the proof, so no session is needed.
+func ConfirmAccountDeletionHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		var payload accountDeleteConfirmRequest
+		if err := e.BindBody(&payload); err != nil {
+			return apis.NewBadRequestError("Invalid request", err)
+		}
+
+		if payload.Token == "" {
+			return apis.NewBadRequestError("Missing token", nil)
+		}
+
+		var user *core.Record
+		var groupIDs []string
+
+		// The token is used up only when the deletion commits
+		err := app.RunInTransaction(func(txApp core.App) error {
+			tokenRecord, err := tokens.Consume(txApp, payload.Token, tokens.ServiceAccountDelete)
+			if err != nil {
+				return err
+			}
+
+			user, err = txApp.FindRecordById("users", tokenRecord.GetString("user"))
+			if err != nil {
+				return err
+			}
+
+			groupIDs, err = deleteAccount(txApp, user)
+			return err
+		})
+		if errors.Is(err, tokens.ErrInvalidToken) {
+			return apis.NewBadRequestError("Invalid or expired token", err)
+		}
+		if err != nil {
+			return apis.NewBadRequestError("Failed to delete account", err)
+		}
+
+		log.Printf("✓ Deleted account %s", user.Id)
+
+		// Telegram is cleaned up after the commit, also when the client disconnects
+		telegramID := telegramIDOf(user)
+		if telegramID != 0 {
+			ctx := context.WithoutCancel(e.Request.Context())
+			removed, flagged := bot.RemoveTelegramAccess(ctx, telegramID, user.GetString("email"), groupIDs)
+
+			if err := audit.Log(app, audit.Entry{
+				Action:           "account.telegram_removed",
+				TargetCollection: "users",
+				Target:           user.Id,
+				Details: map[string]any{
+					"removed_from": removed,
+					"flagged":      flagged,
+				},
+			}); err != nil {
+				log.Printf("Failed to record Telegram removal of %s: %v", user.Id, err)
+			}
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"deleted": true,
+		})
+	}
+}
+
+// telegramIDOf returns the linked Telegram ID of the user, or 0.
+func telegramIDOf(user *core.Record) int64 {
+	var telegramData struct {
+		ID int64 `json:"id"`
+	}
+	if err := user.UnmarshalJSONField("telegram", &telegramData); err != nil {
+		return 0
+	}
+	return telegramData.ID
+}
+
+// deleteAccount anonymizes the requests of the user, hands their guardianships
+// to the group leader or flags them, removes everything that references the
+// user and records the deletion in the audit log. It returns the groups the
+// user had user_groups records for.
+func deleteAccount(app core.App, user *core.Record) ([]string, error) {
+	details := map[string]any{}
+
+	requests, err := app.FindRecordsByFilter(
+		"requests",
+		"email = {:email} && anonymized_at = ''",
+		"",
+		0,
+		0,
+		map[string]any{"email": user.GetString("email")},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range requests {
+		if err := retention.AnonymizeRequest(app, request); err != nil {
+			return nil, err
+		}
+	}
+	details["requests_anonymized"] = len(requests)
+
+	reassigned, orphaned, err := releaseGuardianships(app, user)
+	if err != nil {
+		return nil, err
+	}
+	details["guardianships_reassigned"] = reassigned
+	details["guardianships_flagged"] = orphaned
+
+	groups, err := app.FindRecordsByFilter("groups", "leader = {:user}", "", 0, 0, map[string]any{"user": user.Id})
+	if err != nil {
+		return nil, err
+	}
+	leaderOf := []string{}
+	for _, group := range groups {
+		group.Set("leader", "")
+		if err := app.Save(group); err != nil {
+			return nil, err
+		}
+		leaderOf = append(leaderOf, group.Id)
+	}
+	details["leader_of"] = leaderOf
+
+	groupIDs := []string{}
+	for _, collection := range []string{"tokens", "user_groups"} {
+		records, err := app.FindRecordsByFilter(collection, "user = {:user}", "", 0, 0, map[string]any{"user": user.Id})
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if collection == "user_groups" {
+				groupIDs = append(groupIDs, record.GetString("group"))
+			}
+			if err := app.Delete(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+	details["telegram_linked"] = telegramIDOf(user) != 0
+
+	// Logged before the delete, which clears the actor relation but keeps actor_id
+	if err := audit.Log(app, audit.Entry{
+		Action:           "account.delete",
+		Actor:            user.Id,
+		TargetCollection: "users",
+		Target:           user.Id,
+		Details:          details,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := app.Delete(user); err != nil {
+		return nil, err
+	}
+
+	return groupIDs, nil
+}
+
+// releaseGuardianships moves the guardianships of the user to the leader of the
+// group. Without another leader the guardian is cleared and guardian_removed_at
+// flags the record for the admins. It returns the IDs of both kinds.
+func releaseGuardianships(app core.App, user *core.Record) ([]string, []string, error) {
+	records, err := app.FindRecordsByFilter("guardians", "guardian = {:user}", "", 0, 0, map[string]any{"user": user.Id})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reassigned := []string{}
+	orphaned := []string{}
+	today := time.Now().UTC().Format("2006-01-02")
+
+	for _, record := range records {
+		leaderID := ""
+		if group, err := app.FindRecordById("groups", record.GetString("group")); err == nil {
+			leaderID = group.GetString("leader")
+		}
+
+		note := fmt.Sprintf("%s: guardian deleted their account", today)
+		if leaderID != "" && leaderID != user.Id {
+			record.Set("guardian", leaderID)
+			note += ", reassigned to the group leader"
+			reassigned = append(reassigned, record.Id)
+		} else {
+			record.Set("guardian", "")
+			record.Set("guardian_removed_at", types.NowDateTime())
+			note += ", a new guardian is needed"
+			orphaned = append(orphaned, record.Id)
+		}
+
+		if notes := record.GetString("notes"); notes != "" {
+			note = notes + "\n" + note
+		}
+		record.Set("notes", note)
+
+		if err := app.Save(record); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return reassigned, orphaned, nil
+}
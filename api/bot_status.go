@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/bot"
+)
+
+// BotLockStatusHandler returns which instance holds the Telegram bot lock.
+func BotLockStatusHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		if !authRecord.GetBool("admin") {
+			return apis.NewForbiddenError("Forbidden", nil)
+		}
+
+		status, err := bot.BotLockStatus()
+		if err != nil {
+			return apis.NewBadRequestError("Failed to load bot lock", err)
+		}
+
+		return e.JSON(http.StatusOK, status)
+	}
+}
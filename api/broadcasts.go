@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/bot"
+)
+
+// BroadcastPreviewHandler returns how many users a broadcast would reach.
+func BroadcastPreviewHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		if !authRecord.GetBool("admin") {
+			return apis.NewForbiddenError("Forbidden", nil)
+		}
+
+		broadcast, err := app.FindRecordById("broadcasts", e.Request.PathValue("id"))
+		if err != nil {
+			return apis.NewNotFoundError("Broadcast not found", err)
+		}
+
+		count, err := bot.BroadcastRecipientCount(broadcast)
+		if err != nil {
+			return apis.NewBadRequestError("Failed to resolve recipients", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"id":         broadcast.Id,
+			"recipients": count,
+		})
+	}
+}
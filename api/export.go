@@ -0,0 +1,231 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/audit"
+)
+
+const (
+	// exportLimit is how many exports a member can download per exportWindow.
+	exportLimit  = 3
+	exportWindow = 24 * time.Hour
+)
+
+var errExportLimit = errors.New("export limit reached")
+
+// ExportMyDataHandler returns everything stored about the authenticated user
+// as JSON, or as a ZIP archive with the avatar when format=zip.
+func ExportMyDataHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		format := e.Request.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "zip" {
+			return apis.NewBadRequestError("Unknown format", nil)
+		}
+
+		user, err := app.FindRecordById("users", authRecord.Id)
+		if err != nil {
+			return apis.NewNotFoundError("User not found", err)
+		}
+
+		// The check and the audit entry share a transaction, so concurrent
+		// requests cannot all pass the limit.
+		err = app.RunInTransaction(func(txApp core.App) error {
+			recent, err := txApp.FindRecordsByFilter(
+				"audit_log",
+				"action = 'data.export' && actor_id = {:user} && created > {:since}",
+				"",
+				0,
+				0,
+				map[string]any{
+					"user":  user.Id,
+					"since": types.NowDateTime().Add(-exportWindow),
+				},
+			)
+			if err != nil {
+				return err
+			}
+			if len(recent) >= exportLimit {
+				return errExportLimit
+			}
+
+			return audit.Log(txApp, audit.Entry{
+				Action:           "data.export",
+				Actor:            user.Id,
+				TargetCollection: "users",
+				Target:           user.Id,
+				Details:          map[string]any{"format": format},
+			})
+		})
+		if errors.Is(err, errExportLimit) {
+			return apis.NewTooManyRequestsError("Export limit reached, please try again tomorrow", nil)
+		}
+		if err != nil {
+			return apis.NewBadRequestError("Failed to record export", err)
+		}
+
+		data, err := collectUserData(app, user)
+		if err != nil {
+			return apis.NewBadRequestError("Failed to collect data", err)
+		}
+
+		filename := fmt.Sprintf("export-%s", time.Now().UTC().Format("20060102"))
+
+		if format == "json" {
+			e.Response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+			return e.JSON(http.StatusOK, data)
+		}
+
+		e.Response.Header().Set("Content-Type", "application/zip")
+		e.Response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".zip"))
+		e.Response.WriteHeader(http.StatusOK)
+
+		return writeExportZip(app, e.Response, user, data)
+	}
+}
+
+// collectUserData gathers the user record and every record that refers to it.
+// Token secrets are left out, only their metadata is exported.
+func collectUserData(app *pocketbase.PocketBase, user *core.Record) (map[string]any, error) {
+	user.IgnoreEmailVisibility(true)
+
+	userGroups, err := app.FindRecordsByFilter(
+		"user_groups",
+		"user = {:user}",
+		"",
+		0,
+		0,
+		map[string]any{"user": user.Id},
+	)
+	if err != nil {
+		return nil, err
+	}
+	memberships := make([]map[string]any, 0, len(userGroups))
+	for _, ug := range userGroups {
+		item := ug.PublicExport()
+		if group, err := app.FindRecordById("groups", ug.GetString("group")); err == nil {
+			item["group_name"] = group.GetString("name")
+		}
+		memberships = append(memberships, item)
+	}
+
+	requests, err := app.FindRecordsByFilter(
+		"requests",
+		"email = {:email}",
+		"",
+		0,
+		0,
+		map[string]any{"email": user.GetString("email")},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	guardianFilter := "guardian = {:user}"
+	params := map[string]any{"user": user.Id}
+	for i, request := range requests {
+		key := fmt.Sprintf("request%d", i)
+		guardianFilter += fmt.Sprintf(" || request = {:%s}", key)
+		params[key] = request.Id
+	}
+	guardians, err := app.FindRecordsByFilter("guardians", guardianFilter, "", 0, 0, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenRecords, err := app.FindRecordsByFilter(
+		"tokens",
+		"user = {:user}",
+		"-created",
+		0,
+		0,
+		map[string]any{"user": user.Id},
+	)
+	if err != nil {
+		return nil, err
+	}
+	issuedTokens := make([]map[string]any, 0, len(tokenRecords))
+	for _, token := range tokenRecords {
+		issuedTokens = append(issuedTokens, map[string]any{
+			"id":         token.Id,
+			"service":    token.GetString("service"),
+			"created":    token.GetString("created"),
+			"expires_at": token.GetString("expires_at"),
+			"used_at":    token.GetString("used_at"),
+		})
+	}
+
+	return map[string]any{
+		"exported_at": types.NowDateTime(),
+		"user":        user.PublicExport(),
+		"user_groups": memberships,
+		"requests":    exportRecords(requests),
+		"guardians":   exportRecords(guardians),
+		"tokens":      issuedTokens,
+	}, nil
+}
+
+func exportRecords(records []*core.Record) []map[string]any {
+	items := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		items = append(items, record.PublicExport())
+	}
+	return items
+}
+
+// writeExportZip writes data.json and the avatar file into a ZIP archive.
+func writeExportZip(app *pocketbase.PocketBase, w io.Writer, user *core.Record, data map[string]any) error {
+	archive := zip.NewWriter(w)
+
+	dataFile, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(dataFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return err
+	}
+
+	if avatar := user.GetString("avatar"); avatar != "" {
+		fsys, err := app.NewFilesystem()
+		if err != nil {
+			return err
+		}
+		defer fsys.Close()
+
+		reader, err := fsys.GetReader(user.BaseFilesPath() + "/" + avatar)
+		if err == nil {
+			defer reader.Close()
+
+			avatarFile, err := archive.Create("avatar/" + avatar)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(avatarFile, reader); err != nil {
+				return err
+			}
+		}
+	}
+
+	return archive.Close()
+}
@@ -1,12 +1,14 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
-	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/approvals"
 )
 
 type guardianApprovalRequest struct {
@@ -31,36 +33,17 @@ func LeaderApproveGuardianHandler(app *pocketbase.PocketBase) func(e *core.Reque
 			return apis.NewBadRequestError("Missing request", nil)
 		}
 
-		record, err := app.FindFirstRecordByFilter(
-			"guardians",
-			"request = {:request}",
-			map[string]any{
-				"request": payload.Request,
-			},
-		)
-		if err != nil || record == nil {
+		record, err := approvals.LeaderApprove(app, authRecord.Id, payload.Request, payload.Group)
+		switch {
+		case errors.Is(err, approvals.ErrGuardianNotFound):
 			return apis.NewNotFoundError("Guardian record not found", err)
-		}
-
-		groupID := record.GetString("group")
-		if payload.Group != "" && payload.Group != groupID {
+		case errors.Is(err, approvals.ErrWrongGroup):
 			return apis.NewBadRequestError("User assigned to a different group", nil)
-		}
-
-		group, err := app.FindRecordById("groups", groupID)
-		if err != nil {
+		case errors.Is(err, approvals.ErrGroupNotFound):
 			return apis.NewNotFoundError("Group not found", err)
-		}
-
-		if group.GetString("leader") != authRecord.Id {
+		case errors.Is(err, approvals.ErrNotLeader):
 			return apis.NewForbiddenError("Forbidden", nil)
-		}
-
-		if record.GetDateTime("leader_approved_at").IsZero() {
-			record.Set("leader_approved_at", types.NowDateTime())
-		}
-
-		if err := app.Save(record); err != nil {
+		case err != nil:
 			return apis.NewBadRequestError("Failed to save approval", err)
 		}
 
@@ -82,10 +65,6 @@ func AdminConfirmGuardianHandler(app *pocketbase.PocketBase) func(e *core.Reques
 			return apis.NewUnauthorizedError("Unauthorized", nil)
 		}
 
-		if !authRecord.GetBool("admin") {
-			return apis.NewForbiddenError("Forbidden", nil)
-		}
-
 		var payload guardianApprovalRequest
 		if err := e.BindBody(&payload); err != nil {
 			return apis.NewBadRequestError("Invalid request", err)
@@ -95,26 +74,15 @@ func AdminConfirmGuardianHandler(app *pocketbase.PocketBase) func(e *core.Reques
 			return apis.NewBadRequestError("Missing request", nil)
 		}
 
-		record, err := app.FindFirstRecordByFilter(
-			"guardians",
-			"request = {:request}",
-			map[string]any{
-				"request": payload.Request,
-			},
-		)
-		if err != nil || record == nil {
+		record, err := approvals.AdminConfirm(app, authRecord, payload.Request)
+		switch {
+		case errors.Is(err, approvals.ErrNotAdmin):
+			return apis.NewForbiddenError("Forbidden", nil)
+		case errors.Is(err, approvals.ErrGuardianNotFound):
 			return apis.NewNotFoundError("Guardian record not found", err)
-		}
-
-		if record.GetDateTime("leader_approved_at").IsZero() {
+		case errors.Is(err, approvals.ErrLeaderApproval):
 			return apis.NewBadRequestError("Leader approval required", nil)
-		}
-
-		if record.GetDateTime("admin_confirmed_at").IsZero() {
-			record.Set("admin_confirmed_at", types.NowDateTime())
-		}
-
-		if err := app.Save(record); err != nil {
+		case err != nil:
 			return apis.NewBadRequestError("Failed to save approval", err)
 		}
 
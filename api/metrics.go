@@ -0,0 +1,96 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/metrics"
+)
+
+// metricsRow is one row of a grouped count query.
+type metricsRow struct {
+	Key   string `db:"key"`
+	Label string `db:"label"`
+	Count int    `db:"count"`
+}
+
+// BindMetrics registers the gauges read from the database on every scrape.
+func BindMetrics(app *pocketbase.PocketBase) {
+	metrics.NewGaugeFunc(
+		"members_requests",
+		"Requests by status and region.",
+		[]string{"status", "region"},
+		func() ([]metrics.Sample, error) {
+			var rows []metricsRow
+			err := app.DB().NewQuery(
+				"SELECT [[r.status]] AS [[key]], COALESCE([[rg.name]], '') AS [[label]], COUNT(*) AS [[count]] " +
+					"FROM {{requests}} r LEFT JOIN {{regions}} rg ON [[rg.id]] = [[r.region]] " +
+					"GROUP BY [[r.status]], [[r.region]]",
+			).All(&rows)
+			if err != nil {
+				return nil, err
+			}
+
+			samples := make([]metrics.Sample, 0, len(rows))
+			for _, row := range rows {
+				samples = append(samples, metrics.Sample{Labels: []string{row.Key, row.Label}, Value: float64(row.Count)})
+			}
+			return samples, nil
+		},
+	)
+
+	metrics.NewGaugeFunc(
+		"members_user_groups",
+		"Members per group.",
+		[]string{"group_id", "group"},
+		func() ([]metrics.Sample, error) {
+			var rows []metricsRow
+			err := app.DB().NewQuery(
+				"SELECT [[g.id]] AS [[key]], [[g.name]] AS [[label]], COUNT([[ug.id]]) AS [[count]] " +
+					"FROM {{groups}} g LEFT JOIN {{user_groups}} ug ON [[ug.group]] = [[g.id]] " +
+					"WHERE [[g.disabled_at]] = '' GROUP BY [[g.id]]",
+			).All(&rows)
+			if err != nil {
+				return nil, err
+			}
+
+			samples := make([]metrics.Sample, 0, len(rows))
+			for _, row := range rows {
+				samples = append(samples, metrics.Sample{Labels: []string{row.Key, row.Label}, Value: float64(row.Count)})
+			}
+			return samples, nil
+		},
+	)
+}
+
+// MetricsHandler exposes the metrics in the Prometheus text format.
+// Scrapers send METRICS_TOKEN as a bearer token; without it only admins can read them.
+func MetricsHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		token := os.Getenv("METRICS_TOKEN")
+		given := strings.TrimPrefix(e.Request.Header.Get("Authorization"), "Bearer ")
+		authorized := token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+
+		if !authorized {
+			authRecord := e.Auth
+			if authRecord == nil {
+				return apis.NewUnauthorizedError("Unauthorized", nil)
+			}
+			if !authRecord.GetBool("admin") {
+				return apis.NewForbiddenError("Forbidden", nil)
+			}
+		}
+
+		e.Response.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		e.Response.WriteHeader(http.StatusOK)
+		metrics.Write(e.Response)
+
+		return nil
+	}
+}
@@ -7,6 +7,15 @@ import (
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/metrics"
+)
+
+var requestStatusDuration = metrics.NewSummary(
+	"members_request_status_duration_seconds",
+	"Time requests spent in a status before it changed.",
+	"status",
 )
 
 func BindRequestHooks(app *pocketbase.PocketBase) {
@@ -19,6 +28,7 @@ func BindRequestHooks(app *pocketbase.PocketBase) {
 		}
 
 		record.Set("status", "0-pending")
+		record.Set("status_changed_at", types.NowDateTime())
 		record.Set("group", "")
 
 		return e.Next()
@@ -34,6 +44,13 @@ func BindRequestHooks(app *pocketbase.PocketBase) {
 		newStatus := record.GetString("status")
 		if oldStatus != newStatus {
 			log.Printf("requests hook: status change (id=%s old=%s new=%s)", record.Id, oldStatus, newStatus)
+
+			changedAt := record.Original().GetDateTime("status_changed_at")
+			if changedAt.IsZero() {
+				changedAt = record.Original().GetDateTime("created")
+			}
+			requestStatusDuration.Observe(time.Since(changedAt.Time()).Seconds(), oldStatus)
+			record.Set("status_changed_at", types.NowDateTime())
 		}
 
 		if newStatus != "1-accepted" {
@@ -49,10 +66,10 @@ func BindRequestHooks(app *pocketbase.PocketBase) {
 			return e.Next()
 		}
 
-		groupsFilter := "regions:each ?= {:region} && is_open = true"
+		groupsFilter := "regions:each ?= {:region} && is_open = true && disabled_at = '' && kind != 'channel'"
 		if groupsCollection, err := app.FindCollectionByNameOrId("groups"); err == nil {
 			if groupsCollection.Fields.GetByName("regions") == nil {
-				groupsFilter = "region = {:region} && is_open = true"
+				groupsFilter = "region = {:region} && is_open = true && disabled_at = '' && kind != 'channel'"
 			}
 		}
 
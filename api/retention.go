@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/retention"
+)
+
+// RetentionReportHandler returns what the retention rules would change now,
+// without changing anything.
+func RetentionReportHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		if !authRecord.GetBool("admin") {
+			return apis.NewForbiddenError("Forbidden", nil)
+		}
+
+		settings, err := retention.LoadSettings(app)
+		if err != nil {
+			return apis.NewBadRequestError("Failed to load retention settings", err)
+		}
+
+		results, err := retention.Report(app)
+		if err != nil {
+			return apis.NewBadRequestError("Failed to evaluate retention rules", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"scheduled_dry_run": settings.DryRun,
+			"results":           results,
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/bot"
+)
+
+// StatusHandler returns the bot state and the settings that are missing or invalid.
+// Liveness is served by the built-in /api/health route.
+func StatusHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		if !authRecord.GetBool("admin") {
+			return apis.NewForbiddenError("Forbidden", nil)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"bot":      bot.BotStatus(),
+			"settings": settingsProblems(app),
+		})
+	}
+}
+
+// settingsProblems checks the settings records the app depends on.
+// It returns a problem description per setting name; valid settings are omitted.
+func settingsProblems(app *pocketbase.PocketBase) map[string]string {
+	problems := map[string]string{}
+
+	load := func(name string, dest any) bool {
+		record, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = {:name}",
+			map[string]any{"name": name},
+		)
+		if err != nil {
+			problems[name] = "missing"
+			return false
+		}
+		if err := record.UnmarshalJSONField("data", dest); err != nil {
+			problems[name] = "invalid data: " + err.Error()
+			return false
+		}
+		return true
+	}
+
+	var urlData struct {
+		Address string `json:"address"`
+	}
+	if load("url", &urlData) {
+		parsed, err := url.Parse(urlData.Address)
+		if urlData.Address == "" {
+			problems["url"] = "address is empty"
+		} else if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problems["url"] = "address is not an http(s) URL"
+		}
+	}
+
+	var telegramData struct {
+		Token string `json:"token"`
+		Name  string `json:"name"`
+	}
+	if load("telegram", &telegramData) {
+		if telegramData.Token == "" {
+			problems["telegram"] = "token is empty"
+		} else if telegramData.Name == "" {
+			problems["telegram"] = "name is empty"
+		}
+	}
+
+	messagesData := map[string]any{}
+	if load("bot_messages", &messagesData) {
+		for _, key := range []string{"welcome", "warning"} {
+			if text, _ := messagesData[key].(string); text == "" {
+				problems["bot_messages"] = key + " message is empty"
+				break
+			}
+		}
+	}
+
+	var signupData struct {
+		Steps []map[string]any `json:"steps"`
+	}
+	if load("signup", &signupData) && len(signupData.Steps) == 0 {
+		problems["signup"] = "no steps configured"
+	}
+
+	return problems
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// telegramAuthMaxAge is how long a Telegram login payload stays valid.
+const telegramAuthMaxAge = 24 * time.Hour
+
+type telegramLoginPayload struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+	PhotoURL  string `json:"photo_url"`
+	AuthDate  int64  `json:"auth_date"`
+	Hash      string `json:"hash"`
+}
+
+// TelegramAuthHandler authenticates a user with the Telegram Login Widget payload.
+func TelegramAuthHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		var payload telegramLoginPayload
+		if err := e.BindBody(&payload); err != nil {
+			return apis.NewBadRequestError("Invalid request", err)
+		}
+
+		if payload.ID == 0 || payload.Hash == "" || payload.AuthDate == 0 {
+			return apis.NewBadRequestError("Missing Telegram data", nil)
+		}
+
+		botToken, err := telegramBotToken(app)
+		if err != nil {
+			return apis.NewBadRequestError("Telegram not configured", err)
+		}
+
+		fields := map[string]string{
+			"id":         fmt.Sprintf("%d", payload.ID),
+			"first_name": payload.FirstName,
+			"last_name":  payload.LastName,
+			"username":   payload.Username,
+			"photo_url":  payload.PhotoURL,
+			"auth_date":  fmt.Sprintf("%d", payload.AuthDate),
+		}
+
+		// Login Widget: secret key is SHA256(bot_token)
+		secret := sha256.Sum256([]byte(botToken))
+		if !checkTelegramHash(secret[:], fields, payload.Hash) {
+			return apis.NewUnauthorizedError("Invalid Telegram signature", nil)
+		}
+
+		if !isFreshTelegramAuthDate(payload.AuthDate) {
+			return apis.NewUnauthorizedError("Telegram login expired", nil)
+		}
+
+		user, err := app.FindFirstRecordByFilter(
+			"users",
+			"telegram.id = {:id}",
+			map[string]any{"id": payload.ID},
+		)
+		if err != nil || user == nil {
+			return apis.NewNotFoundError("No account linked to this Telegram user", err)
+		}
+
+		return apis.RecordAuthResponse(e, user, "telegram", nil)
+	}
+}
+
+// telegramBotToken returns the bot token from the telegram setting.
+func telegramBotToken(app *pocketbase.PocketBase) (string, error) {
+	record, err := app.FindFirstRecordByFilter(
+		"settings",
+		"name = 'telegram'",
+		map[string]any{},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var telegramData struct {
+		Token string `json:"token"`
+	}
+	if err := record.UnmarshalJSONField("data", &telegramData); err != nil {
+		return "", err
+	}
+	if telegramData.Token == "" {
+		return "", fmt.Errorf("telegram bot token not configured")
+	}
+
+	return telegramData.Token, nil
+}
+
+// checkTelegramHash verifies a Telegram data-check-string signature.
+// Empty fields are left out, the rest are sorted by key and joined with "\n".
+func checkTelegramHash(secret []byte, fields map[string]string, hash string) bool {
+	keys := make([]string, 0, len(fields))
+	for key, value := range fields {
+		if key == "hash" || value == "" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+fields[key])
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(hash)))
+}
+
+// isFreshTelegramAuthDate rejects stale auth dates and dates too far in the future.
+func isFreshTelegramAuthDate(authDate int64) bool {
+	age := time.Since(time.Unix(authDate, 0))
+	return age <= telegramAuthMaxAge && age >= -time.Minute
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/bot"
+)
+
+type telegramConflictResolvePayload struct {
+	Conflict string `json:"conflict"`
+	Action   string `json:"action"`
+}
+
+// BindUserHooks keeps one Telegram account per user when a users save links one.
+// Saves that keep the Telegram ID are allowed, so users with a duplicate from
+// before this rule stay editable while their conflict is open.
+// Once no duplicates are left, the unique idx_users_telegram_id index also
+// enforces it in the database.
+func BindUserHooks(app *pocketbase.PocketBase) {
+	app.OnRecordValidate("users").BindFunc(func(e *core.RecordEvent) error {
+		var telegramData, originalData struct {
+			ID int64 `json:"id"`
+		}
+		if err := e.Record.UnmarshalJSONField("telegram", &telegramData); err != nil || telegramData.ID == 0 {
+			return e.Next()
+		}
+		if !e.Record.IsNew() {
+			if err := e.Record.Original().UnmarshalJSONField("telegram", &originalData); err == nil && originalData.ID == telegramData.ID {
+				return e.Next()
+			}
+		}
+
+		owner, err := e.App.FindFirstRecordByFilter(
+			"users",
+			"telegram.id = {:id} && id != {:user}",
+			map[string]any{
+				"id":   telegramData.ID,
+				"user": e.Record.Id,
+			},
+		)
+		if err == nil && owner != nil {
+			return fmt.Errorf("telegram account %d is already linked to another user", telegramData.ID)
+		}
+
+		return e.Next()
+	})
+}
+
+// ListTelegramConflictsHandler returns the open Telegram account conflicts.
+func ListTelegramConflictsHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		if !authRecord.GetBool("admin") {
+			return apis.NewForbiddenError("Forbidden", nil)
+		}
+
+		records, err := app.FindRecordsByFilter(
+			"telegram_conflicts",
+			"status = 'open'",
+			"-created",
+			0,
+			0,
+		)
+		if err != nil {
+			return apis.NewBadRequestError("Failed to load conflicts", err)
+		}
+
+		items := make([]map[string]any, 0, len(records))
+		for _, record := range records {
+			item := map[string]any{
+				"id":             record.Id,
+				"created":        record.GetString("created"),
+				"telegram_id":    record.GetInt("telegram_id"),
+				"telegram":       record.Get("telegram"),
+				"existing_user":  record.GetString("existing_user"),
+				"requested_user": record.GetString("requested_user"),
+			}
+			if user, err := app.FindRecordById("users", record.GetString("existing_user")); err == nil {
+				item["existing_email"] = user.GetString("email")
+			}
+			if user, err := app.FindRecordById("users", record.GetString("requested_user")); err == nil {
+				item["requested_email"] = user.GetString("email")
+			}
+			items = append(items, item)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"items": items,
+		})
+	}
+}
+
+// ResolveTelegramConflictHandler applies an admin decision to a Telegram account conflict.
+func ResolveTelegramConflictHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		if !authRecord.GetBool("admin") {
+			return apis.NewForbiddenError("Forbidden", nil)
+		}
+
+		var payload telegramConflictResolvePayload
+		if err := e.BindBody(&payload); err != nil {
+			return apis.NewBadRequestError("Invalid request", err)
+		}
+
+		if payload.Conflict == "" || payload.Action == "" {
+			return apis.NewBadRequestError("Missing conflict or action", nil)
+		}
+
+		switch payload.Action {
+		case bot.ConflictKeepExisting, bot.ConflictTransfer, bot.ConflictMerge:
+		default:
+			return apis.NewBadRequestError("Unknown action", nil)
+		}
+
+		conflict, err := app.FindRecordById("telegram_conflicts", payload.Conflict)
+		if err != nil {
+			return apis.NewNotFoundError("Conflict not found", err)
+		}
+
+		if err := bot.ResolveTelegramConflict(conflict, payload.Action, authRecord); err != nil {
+			return apis.NewBadRequestError("Failed to resolve conflict", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"id":         conflict.Id,
+			"status":     conflict.GetString("status"),
+			"resolution": conflict.GetString("resolution"),
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/bot"
+)
+
+// UnknownMembersHandler returns the unregistered people seen in each group.
+func UnknownMembersHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		if !authRecord.GetBool("admin") {
+			return apis.NewForbiddenError("Forbidden", nil)
+		}
+
+		reports, err := bot.UnknownMembers()
+		if err != nil {
+			return apis.NewBadRequestError("Failed to load unregistered members", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"groups": reports,
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/bot"
+)
+
+// UnlinkTelegramHandler disconnects the Telegram account of the authenticated user.
+func UnlinkTelegramHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		if err := bot.UnlinkTelegramAccount(authRecord); err != nil {
+			return apis.NewBadRequestError("Failed to disconnect Telegram", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"telegram": nil,
+		})
+	}
+}
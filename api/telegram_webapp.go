@@ -0,0 +1,203 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+
+	"members/bot"
+)
+
+// telegramWebAppSessionDuration is how long the limited session of an unlinked Mini App user lasts.
+const telegramWebAppSessionDuration = time.Hour
+
+type telegramWebAppPayload struct {
+	InitData string `json:"init_data"`
+}
+
+type telegramWebAppLinkPayload struct {
+	Session string `json:"session"`
+}
+
+type telegramWebAppUser struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+}
+
+// TelegramWebAppAuthHandler authenticates a Telegram Mini App user via initData.
+// Linked users get a regular auth token, unlinked users get a limited session
+// that can be redeemed with TelegramWebAppLinkHandler after logging in.
+func TelegramWebAppAuthHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		var payload telegramWebAppPayload
+		if err := e.BindBody(&payload); err != nil {
+			return apis.NewBadRequestError("Invalid request", err)
+		}
+
+		values, err := url.ParseQuery(payload.InitData)
+		if err != nil || values.Get("hash") == "" {
+			return apis.NewBadRequestError("Invalid init data", err)
+		}
+
+		botToken, err := telegramBotToken(app)
+		if err != nil {
+			return apis.NewBadRequestError("Telegram not configured", err)
+		}
+
+		fields := map[string]string{}
+		for key := range values {
+			fields[key] = values.Get(key)
+		}
+
+		// Mini App: secret key is HMAC_SHA256("WebAppData", bot_token)
+		secretMac := hmac.New(sha256.New, []byte("WebAppData"))
+		secretMac.Write([]byte(botToken))
+		if !checkTelegramHash(secretMac.Sum(nil), fields, values.Get("hash")) {
+			return apis.NewUnauthorizedError("Invalid Telegram signature", nil)
+		}
+
+		authDate, _ := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+		if !isFreshTelegramAuthDate(authDate) {
+			return apis.NewUnauthorizedError("Telegram session expired", nil)
+		}
+
+		var tgUser telegramWebAppUser
+		if err := json.Unmarshal([]byte(values.Get("user")), &tgUser); err != nil || tgUser.ID == 0 {
+			return apis.NewBadRequestError("Missing Telegram user", err)
+		}
+
+		user, err := app.FindFirstRecordByFilter(
+			"users",
+			"telegram.id = {:id}",
+			map[string]any{"id": tgUser.ID},
+		)
+		if err == nil && user != nil {
+			return apis.RecordAuthResponse(e, user, "telegram", map[string]any{"linked": true})
+		}
+
+		session, err := security.NewJWT(
+			map[string]any{
+				"type":       "telegram_webapp",
+				"id":         tgUser.ID,
+				"username":   tgUser.Username,
+				"first_name": tgUser.FirstName,
+				"last_name":  tgUser.LastName,
+			},
+			telegramWebAppSessionKey(botToken),
+			telegramWebAppSessionDuration,
+		)
+		if err != nil {
+			return apis.NewBadRequestError("Failed to create session", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"linked":  false,
+			"session": session,
+			"telegram": map[string]any{
+				"id":         tgUser.ID,
+				"username":   tgUser.Username,
+				"first_name": tgUser.FirstName,
+				"last_name":  tgUser.LastName,
+			},
+			"connect_url": strings.TrimSuffix(appURL(app), "/") + "/#/login",
+		})
+	}
+}
+
+// TelegramWebAppLinkHandler links the Telegram identity of a limited Mini App
+// session to the authenticated user.
+func TelegramWebAppLinkHandler(app *pocketbase.PocketBase) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		authRecord := e.Auth
+		if authRecord == nil {
+			return apis.NewUnauthorizedError("Unauthorized", nil)
+		}
+
+		var payload telegramWebAppLinkPayload
+		if err := e.BindBody(&payload); err != nil {
+			return apis.NewBadRequestError("Invalid request", err)
+		}
+
+		botToken, err := telegramBotToken(app)
+		if err != nil {
+			return apis.NewBadRequestError("Telegram not configured", err)
+		}
+
+		claims, err := security.ParseJWT(payload.Session, telegramWebAppSessionKey(botToken))
+		if err != nil || claims["type"] != "telegram_webapp" {
+			return apis.NewUnauthorizedError("Invalid or expired session", err)
+		}
+
+		// JSON numbers are decoded as float64
+		telegramID, _ := claims["id"].(float64)
+		if telegramID == 0 {
+			return apis.NewBadRequestError("Missing Telegram user", nil)
+		}
+
+		username, _ := claims["username"].(string)
+		firstName, _ := claims["first_name"].(string)
+		lastName, _ := claims["last_name"].(string)
+
+		err = bot.LinkTelegramAccount(authRecord, &tgbotapi.User{
+			ID:        int64(telegramID),
+			UserName:  username,
+			FirstName: firstName,
+			LastName:  lastName,
+		})
+		if errors.Is(err, bot.ErrTelegramAlreadyLinked) {
+			return apis.NewBadRequestError("Telegram account already linked to another user. An admin will review it.", err)
+		}
+		if err != nil {
+			return apis.NewBadRequestError("Failed to save connection", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"telegram": authRecord.Get("telegram"),
+		})
+	}
+}
+
+// telegramWebAppSessionKey derives the signing key of limited Mini App sessions from the bot token.
+func telegramWebAppSessionKey(botToken string) string {
+	mac := hmac.New(sha256.New, []byte("WebAppSession"))
+	mac.Write([]byte(botToken))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// appURL returns the public address from the url setting.
+func appURL(app *pocketbase.PocketBase) string {
+	address := "http://localhost:8090"
+
+	record, err := app.FindFirstRecordByFilter(
+		"settings",
+		"name = 'url'",
+		map[string]any{},
+	)
+	if err != nil {
+		return address
+	}
+
+	var urlData struct {
+		Address string `json:"address"`
+	}
+	if err := record.UnmarshalJSONField("data", &urlData); err == nil && urlData.Address != "" {
+		address = urlData.Address
+	}
+
+	return address
+}
@@ -0,0 +1,121 @@
+package approvals
+
+import (
+	"errors"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/metrics"
+)
+
+var (
+	ErrGuardianNotFound = errors.New("guardian record not found")
+	ErrWrongGroup       = errors.New("user assigned to a different group")
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrNotLeader        = errors.New("not the leader of the group")
+	ErrNotAdmin         = errors.New("not an admin")
+	ErrLeaderApproval   = errors.New("leader approval required")
+)
+
+var approvalsTotal = metrics.NewCounter("members_guardian_approvals_total", "Guardian approvals by step.", "step")
+
+// FindGuardian returns the guardian record of a request.
+func FindGuardian(app core.App, requestID string) (*core.Record, error) {
+	record, err := app.FindFirstRecordByFilter(
+		"guardians",
+		"request = {:request}",
+		map[string]any{
+			"request": requestID,
+		},
+	)
+	if err != nil || record == nil {
+		return nil, ErrGuardianNotFound
+	}
+
+	return record, nil
+}
+
+// CheckLeader verifies that the user leads the group of the guardian record.
+// An optional groupID must match the group of the record.
+func CheckLeader(app core.App, leaderID string, record *core.Record, groupID string) (*core.Record, error) {
+	recordGroupID := record.GetString("group")
+	if groupID != "" && groupID != recordGroupID {
+		return nil, ErrWrongGroup
+	}
+
+	group, err := app.FindRecordById("groups", recordGroupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	if group.GetString("leader") != leaderID {
+		return nil, ErrNotLeader
+	}
+
+	return group, nil
+}
+
+// LeaderApprove marks leader approval for the guardian record of a request.
+func LeaderApprove(app core.App, leaderID string, requestID string, groupID string) (*core.Record, error) {
+	record, err := FindGuardian(app, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	return LeaderApproveRecord(app, leaderID, record, groupID)
+}
+
+// LeaderApproveRecord marks leader approval for the given guardian record.
+func LeaderApproveRecord(app core.App, leaderID string, record *core.Record, groupID string) (*core.Record, error) {
+	if _, err := CheckLeader(app, leaderID, record, groupID); err != nil {
+		return nil, err
+	}
+
+	approved := record.GetDateTime("leader_approved_at").IsZero()
+	if approved {
+		record.Set("leader_approved_at", types.NowDateTime())
+	}
+
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+
+	if approved {
+		approvalsTotal.Inc("leader")
+	}
+
+	return record, nil
+}
+
+// AdminConfirm marks admin confirmation for the guardian record of a request.
+// The leader must have approved it first.
+func AdminConfirm(app core.App, admin *core.Record, requestID string) (*core.Record, error) {
+	if !admin.GetBool("admin") {
+		return nil, ErrNotAdmin
+	}
+
+	record, err := FindGuardian(app, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.GetDateTime("leader_approved_at").IsZero() {
+		return nil, ErrLeaderApproval
+	}
+
+	confirmed := record.GetDateTime("admin_confirmed_at").IsZero()
+	if confirmed {
+		record.Set("admin_confirmed_at", types.NowDateTime())
+	}
+
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+
+	if confirmed {
+		approvalsTotal.Inc("admin")
+	}
+
+	return record, nil
+}
@@ -0,0 +1,43 @@
+// Package audit records administrative and privacy-relevant actions in the
+// audit_log collection.
+package audit
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Entry is one audit_log record. Actor is empty for scheduled jobs.
+// It is also stored as plain text, so the entry keeps it after the user is deleted.
+type Entry struct {
+	Action           string
+	Actor            string
+	TargetCollection string
+	Target           string
+	Details          map[string]any
+}
+
+// Log saves the entry.
+func Log(app core.App, entry Entry) error {
+	collection, err := app.FindCollectionByNameOrId("audit_log")
+	if err != nil {
+		return fmt.Errorf("audit_log collection not found: %w", err)
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("action", entry.Action)
+	record.Set("actor", entry.Actor)
+	record.Set("actor_id", entry.Actor)
+	record.Set("target_collection", entry.TargetCollection)
+	record.Set("target", entry.Target)
+	if entry.Details != nil {
+		record.Set("details", entry.Details)
+	}
+
+	if err := app.Save(record); err != nil {
+		return fmt.Errorf("failed to save audit entry: %w", err)
+	}
+
+	return nil
+}
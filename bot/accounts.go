@@ -0,0 +1,312 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ErrTelegramAlreadyLinked is returned when the Telegram account belongs to another user.
+var ErrTelegramAlreadyLinked = errors.New("telegram account already linked to another user")
+
+// LinkTelegramAccount stores the Telegram identity on the user and syncs group memberships.
+// When the user was linked to another Telegram account, the old identity is released.
+func LinkTelegramAccount(user *core.Record, from *tgbotapi.User) error {
+	if app == nil {
+		return fmt.Errorf("telegram bot not started")
+	}
+
+	previousID, owner, err := saveTelegramIdentity(app, user, from)
+	if errors.Is(err, ErrTelegramAlreadyLinked) {
+		openTelegramConflict(owner, user, from)
+	}
+	if err != nil {
+		return err
+	}
+
+	completeTelegramLink(user, from, previousID)
+
+	return nil
+}
+
+// saveTelegramIdentity sets the Telegram identity on the user and saves it with
+// txApp, so callers can run it inside their own transaction. It returns the
+// previous Telegram ID of the user, or ErrTelegramAlreadyLinked together with
+// the user that owns the account.
+func saveTelegramIdentity(txApp core.App, user *core.Record, from *tgbotapi.User) (int64, *core.Record, error) {
+	// One Telegram account per user: clashes go to the admin conflict queue
+	owner, err := txApp.FindFirstRecordByFilter(
+		"users",
+		"telegram.id = {:id} && id != {:user}",
+		map[string]any{
+			"id":   from.ID,
+			"user": user.Id,
+		},
+	)
+	if err == nil && owner != nil {
+		return 0, owner, ErrTelegramAlreadyLinked
+	}
+
+	previousID := userTelegramID(user)
+
+	// Prepare Telegram data
+	telegramData := map[string]interface{}{
+		"id":         from.ID,
+		"username":   from.UserName,
+		"first_name": from.FirstName,
+		"last_name":  from.LastName,
+	}
+
+	// Update user's telegram field (PocketBase handles JSON serialization)
+	user.Set("telegram", telegramData)
+	if err := txApp.Save(user); err != nil {
+		return 0, nil, err
+	}
+
+	return previousID, nil, nil
+}
+
+// completeTelegramLink runs the chat side effects of a saved link. It must be
+// called after the save committed.
+func completeTelegramLink(user *core.Record, from *tgbotapi.User, previousID int64) {
+	// Only once the new identity is saved, so a failed save keeps the memberships
+	if previousID != 0 && previousID != from.ID {
+		releaseTelegramIdentity(user, previousID, "replaced by another Telegram account")
+	}
+
+	// Memberships seen before the account was linked
+	applyTelegramSightings(user, from.ID)
+
+	// Sync user group memberships
+	if currentBot() != nil {
+		queueUserSync(user.Id)
+	}
+}
+
+// UnlinkTelegramAccount removes the Telegram identity from the user, removes it
+// from the managed groups and notifies the user and the admins.
+func UnlinkTelegramAccount(user *core.Record) error {
+	if app == nil {
+		return fmt.Errorf("telegram bot not started")
+	}
+
+	telegramID := userTelegramID(user)
+	if telegramID == 0 {
+		return fmt.Errorf("telegram account not linked")
+	}
+
+	user.Set("telegram", nil)
+	if err := app.Save(user); err != nil {
+		return err
+	}
+
+	releaseTelegramIdentity(user, telegramID, "disconnected by the user")
+
+	return nil
+}
+
+// RemoveTelegramAccess removes the Telegram account of a deleted user from
+// every managed chat. groupIDs are the groups the user had user_groups records
+// for; the other active chats are checked with getChatMember, in case the
+// user joined without one. It returns the names of the groups the account was
+// removed from and of those the bot could not remove it from.
+func RemoveTelegramAccess(ctx context.Context, telegramID int64, email string, groupIDs []string) ([]string, []string) {
+	removed := []string{}
+	flagged := []string{}
+
+	if app == nil || telegramID == 0 {
+		return removed, flagged
+	}
+
+	groups, err := app.FindRecordsByFilter("groups", "type = 'telegram'", "name", 0, 0)
+	if err != nil {
+		log.Printf("Failed to load groups: %v", err)
+	}
+
+	for _, group := range groups {
+		name := group.GetString("name")
+		chatID := groupChatID(group)
+
+		if !slices.Contains(groupIDs, group.Id) {
+			if group.GetString("disabled_at") != "" || !isInChat(chatID, telegramID) {
+				continue
+			}
+		}
+
+		if err := removeFromChat(chatID, telegramID); err != nil {
+			log.Printf("Failed to remove Telegram ID %d from group '%s': %v", telegramID, name, err)
+			flagged = append(flagged, name)
+		} else {
+			removed = append(removed, name)
+		}
+
+		if !sleepContext(ctx, syncInterval) {
+			break
+		}
+	}
+
+	reportReleasedIdentity(telegramID, email, "account deleted", removed, flagged)
+
+	return removed, flagged
+}
+
+// isInChat reports whether the Telegram user is currently in the chat.
+func isInChat(chatID int64, telegramID int64) bool {
+	bot := currentBot()
+	if bot == nil || chatID == 0 {
+		return false
+	}
+
+	member, err := bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chatID,
+			UserID: telegramID,
+		},
+	})
+	if err != nil {
+		return false
+	}
+
+	return isInChatStatus(member.Status)
+}
+
+// releaseTelegramIdentity removes an old Telegram identity from every group the
+// user belongs to, deletes the user_groups records and reports the outcome.
+// Groups the bot could not remove the identity from are flagged to the admins.
+func releaseTelegramIdentity(user *core.Record, telegramID int64, reason string) {
+	email := user.GetString("email")
+
+	userGroupRecords, err := app.FindRecordsByFilter(
+		"user_groups",
+		"user = {:user}",
+		"",
+		0,
+		0,
+		map[string]any{"user": user.Id},
+	)
+	if err != nil {
+		log.Printf("Failed to load user_groups for %s: %v", email, err)
+	}
+
+	removed := []string{}
+	flagged := []string{}
+
+	for _, ug := range userGroupRecords {
+		group, err := app.FindRecordById("groups", ug.GetString("group"))
+		if err != nil {
+			app.Delete(ug)
+			continue
+		}
+
+		name := group.GetString("name")
+		if err := removeFromChat(groupChatID(group), telegramID); err != nil {
+			log.Printf("Failed to remove Telegram ID %d from group '%s': %v", telegramID, name, err)
+			flagged = append(flagged, name)
+		} else {
+			removed = append(removed, name)
+		}
+
+		if err := app.Delete(ug); err != nil {
+			log.Printf("Failed to delete user_groups record: %v", err)
+		}
+	}
+
+	reportReleasedIdentity(telegramID, email, reason, removed, flagged)
+}
+
+// reportReleasedIdentity tells the Telegram account and the admins which
+// groups it was removed from. Groups it could not be removed from are flagged.
+func reportReleasedIdentity(telegramID int64, email string, reason string, removed []string, flagged []string) {
+	log.Printf("✓ Released Telegram ID %d from user %s (%s)", telegramID, email, reason)
+
+	sendDirectMessage(telegramID, fmt.Sprintf(
+		"ℹ️ This Telegram account is no longer connected to %s (%s).\n\nYou have been removed from the community groups.",
+		email,
+		reason,
+	))
+
+	summary := fmt.Sprintf("ℹ️ Telegram ID %d of %s was %s.", telegramID, email, reason)
+	if len(removed) > 0 {
+		summary += "\n\nRemoved from: " + strings.Join(removed, ", ")
+	}
+	if len(flagged) > 0 {
+		summary += "\n\n⚠️ Could not remove from: " + strings.Join(flagged, ", ") + "\nPlease check these groups manually."
+	}
+	notifyAdmins(summary)
+}
+
+// removeFromChat kicks a user from a chat without a permanent ban.
+func removeFromChat(chatID int64, telegramID int64) error {
+	bot := currentBot()
+	if bot == nil {
+		return fmt.Errorf("telegram bot not started")
+	}
+	if chatID == 0 {
+		return fmt.Errorf("group has no chat_id")
+	}
+
+	member := tgbotapi.ChatMemberConfig{ChatID: chatID, UserID: telegramID}
+	if _, err := bot.Request(tgbotapi.BanChatMemberConfig{ChatMemberConfig: member}); err != nil {
+		return err
+	}
+
+	// Unban right away so the user can join again later
+	_, err := bot.Request(tgbotapi.UnbanChatMemberConfig{ChatMemberConfig: member, OnlyIfBanned: true})
+	return err
+}
+
+// sendDirectMessage sends a private message to a Telegram user.
+func sendDirectMessage(telegramID int64, text string) {
+	bot := currentBot()
+	if bot == nil || telegramID == 0 || text == "" {
+		return
+	}
+
+	if _, err := bot.Send(tgbotapi.NewMessage(telegramID, text)); err != nil {
+		log.Printf("Failed to send message to Telegram ID %d: %v", telegramID, err)
+	}
+}
+
+// notifyAdmins sends a private message to every admin with a linked Telegram account.
+func notifyAdmins(text string) {
+	admins, err := app.FindRecordsByFilter("users", "admin = true", "", 0, 0)
+	if err != nil {
+		log.Printf("Failed to load admins: %v", err)
+		return
+	}
+
+	for _, admin := range admins {
+		sendDirectMessage(userTelegramID(admin), text)
+	}
+}
+
+// userTelegramID returns the linked Telegram ID of the user, or 0.
+func userTelegramID(user *core.Record) int64 {
+	var telegramData struct {
+		ID int64 `json:"id"`
+	}
+	if err := user.UnmarshalJSONField("telegram", &telegramData); err != nil {
+		return 0
+	}
+	return telegramData.ID
+}
+
+// groupChatID returns the Telegram chat ID of the group, or 0.
+func groupChatID(group *core.Record) int64 {
+	var telegramGroupData struct {
+		ChatID string `json:"chat_id"`
+	}
+	if err := group.UnmarshalJSONField("telegram", &telegramGroupData); err != nil {
+		return 0
+	}
+
+	var chatID int64
+	fmt.Sscanf(telegramGroupData.ChatID, "%d", &chatID)
+	return chatID
+}
@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase/core"
+
+	"members/approvals"
+)
+
+const adminHelp = "Admin commands:\n/stats - requests and members overview\n/pending - requests waiting for admin confirmation\n/confirm REQUEST_ID - confirm a guardian record\n/unknown - unregistered people in groups"
+
+var adminCommands = map[string]bool{
+	"stats":   true,
+	"pending": true,
+	"confirm": true,
+	"unknown": true,
+}
+
+// handleAdminCommand runs an admin command for a linked user.
+// The user must be an admin before anything is read.
+func handleAdminCommand(message *tgbotapi.Message, user *core.Record) {
+	if !user.GetBool("admin") {
+		sendDirectMessage(message.Chat.ID, "❌ This command is for admins only.")
+		return
+	}
+
+	var reply string
+	switch message.Command() {
+	case "stats":
+		reply = statsReply()
+	case "pending":
+		reply = pendingReply()
+	case "confirm":
+		reply = confirmReply(user, strings.TrimSpace(message.CommandArguments()))
+	case "unknown":
+		reply = unknownReply()
+	}
+
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, reply)
+	msg.DisableWebPagePreview = true
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("Failed to reply to /%s: %v", message.Command(), err)
+	}
+}
+
+// countRow is one row of a grouped count query.
+type countRow struct {
+	Key   string `db:"key"`
+	Count int    `db:"count"`
+}
+
+func statsReply() string {
+	var byStatus, byRegion []countRow
+	err := app.DB().NewQuery("SELECT [[status]] AS [[key]], COUNT(*) AS [[count]] FROM {{requests}} GROUP BY [[status]] ORDER BY [[status]]").All(&byStatus)
+	if err == nil {
+		err = app.DB().NewQuery(
+			"SELECT COALESCE([[rg.name]], '-') AS [[key]], COUNT(*) AS [[count]] " +
+				"FROM {{requests}} r LEFT JOIN {{regions}} rg ON [[rg.id]] = [[r.region]] " +
+				"GROUP BY [[r.region]] ORDER BY [[key]]",
+		).All(&byRegion)
+	}
+	if err != nil {
+		return "❌ Failed to load requests."
+	}
+
+	total := 0
+	for _, row := range byStatus {
+		total += row.Count
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 Requests: %d\n", total)
+	for _, row := range byStatus {
+		fmt.Fprintf(&b, "• %s: %d\n", row.Key, row.Count)
+	}
+
+	b.WriteString("\n🗺 By region\n")
+	for _, row := range byRegion {
+		fmt.Fprintf(&b, "• %s: %d\n", row.Key, row.Count)
+	}
+
+	// Soft-disabled groups are left out
+	b.WriteString("\n👥 Members by group\n")
+	var byGroup []countRow
+	err = app.DB().NewQuery(
+		"SELECT [[g.name]] AS [[key]], COUNT([[ug.id]]) AS [[count]] " +
+			"FROM {{groups}} g LEFT JOIN {{user_groups}} ug ON [[ug.group]] = [[g.id]] " +
+			"WHERE [[g.disabled_at]] = '' GROUP BY [[g.id]] ORDER BY [[g.name]]",
+	).All(&byGroup)
+	if err == nil {
+		for _, row := range byGroup {
+			fmt.Fprintf(&b, "• %s: %d\n", row.Key, row.Count)
+		}
+	}
+
+	return b.String()
+}
+
+func pendingReply() string {
+	records, err := app.FindRecordsByFilter(
+		"guardians",
+		"leader_approved_at != '' && admin_confirmed_at = ''",
+		"leader_approved_at",
+		0,
+		0,
+	)
+	if err != nil {
+		return "❌ Failed to load pending requests."
+	}
+	if len(records) == 0 {
+		return "✅ Nothing waiting for admin confirmation."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "⏳ Waiting for admin confirmation: %d\n", len(records))
+	for _, record := range records {
+		name := "-"
+		if request, err := app.FindRecordById("requests", record.GetString("request")); err == nil {
+			name = request.GetString("name")
+		}
+		groupName := "-"
+		if group, err := app.FindRecordById("groups", record.GetString("group")); err == nil {
+			groupName = group.GetString("name")
+		}
+		fmt.Fprintf(&b, "\n• %s (%s)\n  /confirm %s", name, groupName, record.GetString("request"))
+	}
+
+	return b.String()
+}
+
+func confirmReply(admin *core.Record, requestID string) string {
+	if requestID == "" {
+		return "Usage: /confirm REQUEST_ID"
+	}
+
+	record, err := approvals.AdminConfirm(app, admin, requestID)
+	switch {
+	case errors.Is(err, approvals.ErrGuardianNotFound):
+		return "❌ Guardian record not found."
+	case errors.Is(err, approvals.ErrLeaderApproval):
+		return "❌ Leader approval required."
+	case err != nil:
+		return "❌ Failed to save approval."
+	}
+
+	log.Printf("✓ Admin %s confirmed guardian %s from Telegram", admin.GetString("email"), record.Id)
+
+	return fmt.Sprintf("✅ Confirmed request %s.", requestID)
+}
@@ -0,0 +1,254 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/approvals"
+)
+
+const (
+	callbackLeaderApprove = "leader_approve"
+	callbackLeaderInfo    = "leader_info"
+)
+
+// BindGuardianHooks notifies group leaders when a guardian record is ready for approval.
+func BindGuardianHooks(pbApp *pocketbase.PocketBase) {
+	notify := func(e *core.RecordEvent) error {
+		// The save that asks for more info clears leader_notified_at, the
+		// leader is notified again on the next update of the guardian
+		infoRequested := !e.Record.IsNew() &&
+			!e.Record.Original().GetDateTime("leader_notified_at").IsZero() &&
+			e.Record.GetDateTime("leader_notified_at").IsZero()
+
+		if currentBot() != nil && !infoRequested && guardianReady(e.Record) {
+			guardianID := e.Record.Id
+			goTask("leader notification", func(ctx context.Context) {
+				notifyLeaderOfGuardian(guardianID)
+			})
+		}
+		return e.Next()
+	}
+
+	pbApp.OnRecordAfterCreateSuccess("guardians").BindFunc(notify)
+	pbApp.OnRecordAfterUpdateSuccess("guardians").BindFunc(notify)
+}
+
+// guardianReady reports whether all guardian steps are done and the leader
+// has neither approved nor been notified yet.
+func guardianReady(record *core.Record) bool {
+	if !record.GetDateTime("leader_approved_at").IsZero() || !record.GetDateTime("leader_notified_at").IsZero() {
+		return false
+	}
+
+	return guardianStepsDone(record)
+}
+
+// guardianStepsDone reports whether the guardian completed every step.
+func guardianStepsDone(record *core.Record) bool {
+	steps := map[string]struct {
+		Done bool `json:"done"`
+	}{}
+	if err := record.UnmarshalJSONField("steps", &steps); err != nil || len(steps) == 0 {
+		return false
+	}
+
+	for _, step := range steps {
+		if !step.Done {
+			return false
+		}
+	}
+
+	return true
+}
+
+func notifyLeaderOfGuardian(guardianID string) {
+	record, err := app.FindRecordById("guardians", guardianID)
+	if err != nil || !guardianReady(record) {
+		return
+	}
+
+	group, err := app.FindRecordById("groups", record.GetString("group"))
+	if err != nil {
+		return
+	}
+
+	leader, err := app.FindRecordById("users", group.GetString("leader"))
+	if err != nil {
+		log.Printf("Guardian %s ready but group '%s' has no leader", record.Id, group.GetString("name"))
+		return
+	}
+
+	leaderTelegramID := userTelegramID(leader)
+	if leaderTelegramID == 0 {
+		log.Printf("Guardian %s ready but leader %s has no Telegram", record.Id, leader.GetString("email"))
+		return
+	}
+
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(leaderTelegramID, guardianSummary(record, group))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Approve", callbackLeaderApprove+":"+record.Id),
+			tgbotapi.NewInlineKeyboardButtonData("❓ Need more info", callbackLeaderInfo+":"+record.Id),
+		),
+	)
+
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("Failed to notify leader %s: %v", leader.GetString("email"), err)
+		return
+	}
+
+	record.Set("leader_notified_at", types.NowDateTime())
+	if err := app.Save(record); err != nil {
+		log.Printf("Failed to save leader_notified_at: %v", err)
+	}
+
+	log.Printf("✓ Notified leader %s about guardian %s", leader.GetString("email"), record.Id)
+}
+
+// guardianSummary describes a guardian record for leader and admin messages.
+func guardianSummary(record *core.Record, group *core.Record) string {
+	applicant := "-"
+	if request, err := app.FindRecordById("requests", record.GetString("request")); err == nil {
+		applicant = fmt.Sprintf("%s (%s)", request.GetString("name"), request.GetString("email"))
+	}
+
+	guardianName := "-"
+	if guardian, err := app.FindRecordById("users", record.GetString("guardian")); err == nil {
+		guardianName = guardian.GetString("email")
+		if name := guardian.GetString("name"); name != "" {
+			guardianName = fmt.Sprintf("%s (%s)", name, guardianName)
+		}
+	}
+
+	return fmt.Sprintf(
+		"🛡 Guardian record ready\n\nApplicant: %s\nGroup: %s\nGuardian: %s",
+		applicant,
+		group.GetString("name"),
+		guardianName,
+	)
+}
+
+func handleCallbackQuery(query *tgbotapi.CallbackQuery) {
+	action, guardianID, _ := strings.Cut(query.Data, ":")
+
+	switch action {
+	case callbackLeaderApprove, callbackLeaderInfo:
+		handleLeaderCallback(query, action, guardianID)
+	case callbackUnsubscribe:
+		handleUnsubscribeCallback(query)
+	default:
+		answerCallback(query, "")
+	}
+}
+
+func handleLeaderCallback(query *tgbotapi.CallbackQuery, action string, guardianID string) {
+	user, err := app.FindFirstRecordByFilter(
+		"users",
+		"telegram.id = {:id}",
+		map[string]any{"id": query.From.ID},
+	)
+	if err != nil || user == nil {
+		answerCallback(query, "❌ Your Telegram account is not connected.")
+		return
+	}
+
+	record, err := app.FindRecordById("guardians", guardianID)
+	if err != nil {
+		answerCallback(query, "❌ Guardian record not found.")
+		return
+	}
+
+	group, err := approvals.CheckLeader(app, user.Id, record, "")
+	if err != nil {
+		answerCallback(query, leaderErrorText(err))
+		return
+	}
+
+	summary := guardianSummary(record, group)
+
+	if action == callbackLeaderApprove {
+		if _, err := approvals.LeaderApproveRecord(app, user.Id, record, ""); err != nil {
+			answerCallback(query, leaderErrorText(err))
+			return
+		}
+
+		answerCallback(query, "✅ Approved")
+		editCallbackMessage(query, summary+"\n\n✅ Approved by you.")
+		log.Printf("✓ Leader %s approved guardian %s from Telegram", user.GetString("email"), record.Id)
+		return
+	}
+
+	note := fmt.Sprintf("[%s] Leader requested more info.", types.NowDateTime().Time().Format("2006-01-02"))
+	if notes := record.GetString("notes"); notes != "" {
+		note = notes + "\n" + note
+	}
+	record.Set("notes", note)
+	// Lets the next completed update notify the leader again
+	record.Set("leader_notified_at", nil)
+	if err := app.Save(record); err != nil {
+		answerCallback(query, "❌ Failed to save.")
+		return
+	}
+
+	if guardian, err := app.FindRecordById("users", record.GetString("guardian")); err == nil {
+		sendDirectMessage(userTelegramID(guardian), fmt.Sprintf(
+			"❓ The leader of %s needs more information about your guardian record.\n\n%s",
+			group.GetString("name"),
+			appURL(),
+		))
+	}
+
+	answerCallback(query, "❓ More info requested")
+	editCallbackMessage(query, summary+"\n\n❓ More info requested.")
+}
+
+func leaderErrorText(err error) string {
+	switch {
+	case errors.Is(err, approvals.ErrGuardianNotFound):
+		return "❌ Guardian record not found."
+	case errors.Is(err, approvals.ErrGroupNotFound):
+		return "❌ Group not found."
+	case errors.Is(err, approvals.ErrNotLeader):
+		return "❌ You are not the leader of this group."
+	default:
+		return "❌ Failed to save approval."
+	}
+}
+
+func answerCallback(query *tgbotapi.CallbackQuery, text string) {
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
+
+	if _, err := bot.Request(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		log.Printf("Failed to answer callback: %v", err)
+	}
+}
+
+// editCallbackMessage replaces the text of the callback message and drops its keyboard.
+func editCallbackMessage(query *tgbotapi.CallbackQuery, text string) {
+	bot := currentBot()
+	if query.Message == nil || bot == nil {
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	if _, err := bot.Send(edit); err != nil {
+		log.Printf("Failed to edit callback message: %v", err)
+	}
+}
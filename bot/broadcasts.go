@@ -0,0 +1,423 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// broadcastInterval keeps direct messages well below Telegram's ~30 msg/s limit.
+const broadcastInterval = 50 * time.Millisecond
+
+const callbackUnsubscribe = "unsubscribe"
+
+// BroadcastFilter selects the recipients of a broadcast.
+// Empty lists do not filter. Roles are user_groups roles ("member", "admin")
+// or "leader" for group leaders; they apply to the selected groups/regions.
+type BroadcastFilter struct {
+	Regions         []string `json:"regions"`
+	Groups          []string `json:"groups"`
+	UserStatuses    []string `json:"user_statuses"`
+	RequestStatuses []string `json:"request_statuses"`
+	Roles           []string `json:"roles"`
+}
+
+var broadcastMu sync.Mutex
+
+// errBroadcastInterrupted is returned when shutdown cancels a rate limit wait.
+var errBroadcastInterrupted = errors.New("broadcast interrupted")
+
+// BindBroadcastJob registers the cron job that delivers queued broadcasts.
+func BindBroadcastJob(pbApp *pocketbase.PocketBase) {
+	pbApp.Cron().MustAdd("broadcasts", "* * * * *", func() {
+		if currentBot() == nil {
+			return
+		}
+		runTask("broadcasts", processBroadcasts)
+	})
+}
+
+// processBroadcasts delivers queued broadcasts and resumes interrupted ones.
+func processBroadcasts(ctx context.Context) {
+	// Skip if a previous run is still sending
+	if !broadcastMu.TryLock() {
+		return
+	}
+	defer broadcastMu.Unlock()
+
+	broadcasts, err := app.FindRecordsByFilter(
+		"broadcasts",
+		"status = 'queued' || status = 'sending'",
+		"created",
+		0,
+		0,
+	)
+	if err != nil {
+		return
+	}
+
+	for _, broadcast := range broadcasts {
+		if broadcast.GetString("status") == "queued" {
+			if err := queueBroadcastDeliveries(broadcast); err != nil {
+				log.Printf("Failed to queue broadcast '%s': %v", broadcast.GetString("title"), err)
+				continue
+			}
+		}
+
+		sendBroadcastDeliveries(ctx, broadcast)
+	}
+}
+
+// queueBroadcastDeliveries creates a pending delivery for every recipient
+// and moves the broadcast to "sending".
+func queueBroadcastDeliveries(broadcast *core.Record) error {
+	var filter BroadcastFilter
+	broadcast.UnmarshalJSONField("filter", &filter)
+
+	recipients, err := BroadcastRecipients(filter)
+	if err != nil {
+		return err
+	}
+
+	collection, err := app.FindCollectionByNameOrId("broadcast_deliveries")
+	if err != nil {
+		return err
+	}
+
+	return app.RunInTransaction(func(txApp core.App) error {
+		for _, user := range recipients {
+			delivery := core.NewRecord(collection)
+			delivery.Set("broadcast", broadcast.Id)
+			delivery.Set("user", user.Id)
+			delivery.Set("status", "pending")
+			if err := txApp.Save(delivery); err != nil {
+				return err
+			}
+		}
+
+		broadcast.Set("status", "sending")
+		broadcast.Set("started_at", types.NowDateTime())
+		return txApp.Save(broadcast)
+	})
+}
+
+// sendBroadcastDeliveries sends the pending deliveries of a broadcast.
+// On shutdown the broadcast stays "sending" and is resumed by the next run.
+func sendBroadcastDeliveries(ctx context.Context, broadcast *core.Record) {
+	deliveries, err := app.FindRecordsByFilter(
+		"broadcast_deliveries",
+		"broadcast = {:broadcast} && status = 'pending'",
+		"created",
+		0,
+		0,
+		map[string]any{"broadcast": broadcast.Id},
+	)
+	if err != nil {
+		log.Printf("Failed to load deliveries for broadcast '%s': %v", broadcast.GetString("title"), err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if !deliverBroadcast(ctx, broadcast, delivery) || !sleepContext(ctx, broadcastInterval) {
+			log.Printf("Broadcast '%s' interrupted, it will resume on the next run", broadcast.GetString("title"))
+			return
+		}
+	}
+
+	sent, _ := app.FindRecordsByFilter(
+		"broadcast_deliveries",
+		"broadcast = {:broadcast} && status = 'sent'",
+		"",
+		0,
+		0,
+		map[string]any{"broadcast": broadcast.Id},
+	)
+	failed, _ := app.FindRecordsByFilter(
+		"broadcast_deliveries",
+		"broadcast = {:broadcast} && status = 'failed'",
+		"",
+		0,
+		0,
+		map[string]any{"broadcast": broadcast.Id},
+	)
+
+	broadcast.Set("status", "done")
+	broadcast.Set("finished_at", types.NowDateTime())
+	broadcast.Set("sent_count", len(sent))
+	broadcast.Set("failed_count", len(failed))
+	if err := app.Save(broadcast); err != nil {
+		log.Printf("Failed to save broadcast: %v", err)
+		return
+	}
+
+	log.Printf("✓ Broadcast '%s' done (sent=%d failed=%d)", broadcast.GetString("title"), len(sent), len(failed))
+}
+
+// deliverBroadcast sends one delivery and saves its outcome. It returns false
+// when shutdown interrupted a rate limit wait; the delivery then stays pending.
+func deliverBroadcast(ctx context.Context, broadcast *core.Record, delivery *core.Record) bool {
+	user, err := app.FindRecordById("users", delivery.GetString("user"))
+	telegramID := int64(0)
+	if err == nil {
+		telegramID = userTelegramID(user)
+	}
+
+	switch {
+	case err != nil || telegramID == 0:
+		delivery.Set("status", "skipped")
+		delivery.Set("error", "no linked Telegram account")
+	case user.GetBool("broadcast_opt_out"):
+		delivery.Set("status", "skipped")
+		delivery.Set("error", "unsubscribed")
+	default:
+		err := sendBroadcastMessage(ctx, telegramID, renderBroadcast(broadcast, user))
+		if errors.Is(err, errBroadcastInterrupted) {
+			return false
+		}
+		if err != nil {
+			delivery.Set("status", "failed")
+			delivery.Set("error", err.Error())
+		} else {
+			delivery.Set("status", "sent")
+			delivery.Set("sent_at", types.NowDateTime())
+		}
+	}
+
+	if err := app.Save(delivery); err != nil {
+		log.Printf("Failed to save broadcast delivery: %v", err)
+	}
+
+	return true
+}
+
+func renderBroadcast(broadcast *core.Record, user *core.Record) string {
+	name := user.GetString("name")
+	if name == "" {
+		name = user.GetString("email")
+	}
+
+	text := broadcast.GetString("body")
+	text = strings.ReplaceAll(text, "{name}", name)
+	text = strings.ReplaceAll(text, "{email}", user.GetString("email"))
+	text = strings.ReplaceAll(text, "{url}", appURL())
+
+	return text
+}
+
+// sendBroadcastMessage sends a broadcast with an unsubscribe button.
+// Rate limit errors are retried once after the delay asked by Telegram,
+// unless ctx is cancelled during the wait.
+func sendBroadcastMessage(ctx context.Context, telegramID int64, text string) error {
+	msg := tgbotapi.NewMessage(telegramID, text)
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔕 Unsubscribe", callbackUnsubscribe),
+		),
+	)
+
+	bot := currentBot()
+	if bot == nil {
+		return fmt.Errorf("telegram bot not started")
+	}
+
+	_, err := bot.Send(msg)
+
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) && tgErr.RetryAfter > 0 {
+		telegramRetriesTotal.Inc("rate_limit")
+		if !sleepContext(ctx, time.Duration(tgErr.RetryAfter)*time.Second) {
+			return errBroadcastInterrupted
+		}
+		_, err = bot.Send(msg)
+	}
+
+	return err
+}
+
+// BroadcastRecipients returns the users with a linked Telegram account matching the filter.
+func BroadcastRecipients(filter BroadcastFilter) ([]*core.Record, error) {
+	users, err := app.FindRecordsByFilter(
+		"users",
+		"telegram.id != null && telegram.id != '' && broadcast_opt_out != true",
+		"",
+		0,
+		0,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed map[string]bool
+	if len(filter.Groups) > 0 || len(filter.Regions) > 0 || len(filter.Roles) > 0 {
+		allowed, err = broadcastMembers(filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var requestEmails map[string]bool
+	if len(filter.RequestStatuses) > 0 {
+		requestEmails = map[string]bool{}
+		for _, status := range filter.RequestStatuses {
+			requests, err := app.FindRecordsByFilter(
+				"requests",
+				"status = {:status}",
+				"",
+				0,
+				0,
+				map[string]any{"status": status},
+			)
+			if err != nil {
+				return nil, err
+			}
+			for _, request := range requests {
+				requestEmails[strings.ToLower(request.GetString("email"))] = true
+			}
+		}
+	}
+
+	recipients := []*core.Record{}
+	for _, user := range users {
+		if allowed != nil && !allowed[user.Id] {
+			continue
+		}
+		if requestEmails != nil && !requestEmails[strings.ToLower(user.GetString("email"))] {
+			continue
+		}
+		if len(filter.UserStatuses) > 0 && !containsString(filter.UserStatuses, user.GetString("status")) {
+			continue
+		}
+		recipients = append(recipients, user)
+	}
+
+	return recipients, nil
+}
+
+// broadcastMembers returns the IDs of the users matching the group, region and role filters.
+func broadcastMembers(filter BroadcastFilter) (map[string]bool, error) {
+	groups, err := app.FindRecordsByFilter("groups", "disabled_at = ''", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	memberRoles := []string{}
+	leaders := false
+	for _, role := range filter.Roles {
+		if role == "leader" {
+			leaders = true
+			continue
+		}
+		memberRoles = append(memberRoles, role)
+	}
+	members := len(filter.Roles) == 0 || len(memberRoles) > 0
+
+	allowed := map[string]bool{}
+	for _, group := range groups {
+		if len(filter.Groups) > 0 && !containsString(filter.Groups, group.Id) {
+			continue
+		}
+		if len(filter.Regions) > 0 && !containsAny(filter.Regions, group.GetStringSlice("regions")) {
+			continue
+		}
+
+		if leaders && group.GetString("leader") != "" {
+			allowed[group.GetString("leader")] = true
+		}
+
+		if !members {
+			continue
+		}
+
+		userGroupRecords, err := app.FindRecordsByFilter(
+			"user_groups",
+			"group = {:group}",
+			"",
+			0,
+			0,
+			map[string]any{"group": group.Id},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ug := range userGroupRecords {
+			if len(memberRoles) > 0 && !containsString(memberRoles, ug.GetString("role")) {
+				continue
+			}
+			allowed[ug.GetString("user")] = true
+		}
+	}
+
+	return allowed, nil
+}
+
+// setBroadcastOptOut updates the broadcast subscription of the user.
+func setBroadcastOptOut(user *core.Record, optOut bool) error {
+	user.Set("broadcast_opt_out", optOut)
+	return app.Save(user)
+}
+
+func handleUnsubscribeCallback(query *tgbotapi.CallbackQuery) {
+	user, err := app.FindFirstRecordByFilter(
+		"users",
+		"telegram.id = {:id}",
+		map[string]any{"id": query.From.ID},
+	)
+	if err != nil || user == nil {
+		answerCallback(query, "❌ Your Telegram account is not connected.")
+		return
+	}
+
+	if err := setBroadcastOptOut(user, true); err != nil {
+		answerCallback(query, "❌ Failed to save.")
+		return
+	}
+
+	answerCallback(query, "🔕 Unsubscribed")
+	sendDirectMessage(query.From.ID, botMessage("unsubscribed", nil))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// BroadcastRecipientCount returns how many users a broadcast would reach.
+func BroadcastRecipientCount(broadcast *core.Record) (int, error) {
+	if app == nil {
+		return 0, fmt.Errorf("telegram bot not started")
+	}
+
+	var filter BroadcastFilter
+	broadcast.UnmarshalJSONField("filter", &filter)
+
+	recipients, err := BroadcastRecipients(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(recipients), nil
+}
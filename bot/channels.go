@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// isManagedChat reports whether the bot tracks chats of this type.
+func isManagedChat(chatType string) bool {
+	return chatType == "group" || chatType == "supergroup" || chatType == "channel"
+}
+
+// chatKind maps a Telegram chat type to the groups.kind value.
+func chatKind(chatType string) string {
+	if chatType == "channel" {
+		return "channel"
+	}
+	return "group"
+}
+
+func findGroupByChatID(chatID int64) (*core.Record, error) {
+	return app.FindFirstRecordByFilter(
+		"groups",
+		"telegram.chat_id = {:id}",
+		map[string]any{"id": fmt.Sprintf("%d", chatID)},
+	)
+}
+
+// isActiveMember reports whether a linked user may stay in members-only chats.
+func isActiveMember(user *core.Record) bool {
+	return user != nil && user.GetString("status") != "suspended"
+}
+
+// enforceMembersOnly removes unknown or suspended users who join a members-only chat.
+// It returns true when the user was removed.
+func enforceMembersOnly(update *tgbotapi.ChatMemberUpdated, user *core.Record) bool {
+	newStatus := update.NewChatMember.Status
+	if newStatus != "member" && newStatus != "restricted" {
+		return false
+	}
+	if isActiveMember(user) {
+		return false
+	}
+
+	group, err := findGroupByChatID(update.Chat.ID)
+	if err != nil || group.GetString("gate_mode") != "members_only" {
+		return false
+	}
+
+	telegramID := update.NewChatMember.User.ID
+	if err := removeFromChat(update.Chat.ID, telegramID); err != nil {
+		log.Printf("Failed to remove Telegram ID %d from members-only '%s': %v", telegramID, group.GetString("name"), err)
+		return false
+	}
+
+	log.Printf("✓ Removed unregistered Telegram ID %d from members-only '%s'", telegramID, group.GetString("name"))
+	sendDirectMessage(telegramID, botMessage("members_only", nil))
+
+	return true
+}
+
+// handleChatJoinRequest approves join requests of active members in members-only chats
+// and declines the others. Other chats are left to their Telegram admins.
+func handleChatJoinRequest(request *tgbotapi.ChatJoinRequest) {
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
+
+	group, err := findGroupByChatID(request.Chat.ID)
+	if err != nil || group.GetString("gate_mode") != "members_only" {
+		return
+	}
+
+	user, _ := app.FindFirstRecordByFilter(
+		"users",
+		"telegram.id = {:id}",
+		map[string]any{"id": request.From.ID},
+	)
+
+	chatConfig := tgbotapi.ChatConfig{ChatID: request.Chat.ID}
+
+	if isActiveMember(user) {
+		if _, err := bot.Request(tgbotapi.ApproveChatJoinRequestConfig{ChatConfig: chatConfig, UserID: request.From.ID}); err != nil {
+			log.Printf("Failed to approve join request: %v", err)
+			return
+		}
+		log.Printf("✓ Approved join request of %s for '%s'", user.GetString("email"), group.GetString("name"))
+		return
+	}
+
+	if _, err := bot.Request(tgbotapi.DeclineChatJoinRequest{ChatConfig: chatConfig, UserID: request.From.ID}); err != nil {
+		log.Printf("Failed to decline join request: %v", err)
+		return
+	}
+
+	log.Printf("✓ Declined join request of Telegram ID %d for '%s'", request.From.ID, group.GetString("name"))
+	sendDirectMessage(request.From.ID, botMessage("members_only", nil))
+}
+
+// handleChannelPost handles service messages posted in channels.
+func handleChannelPost(post *tgbotapi.Message) {
+	if post.NewChatTitle != "" {
+		updateGroupName(post.Chat.ID, post.NewChatTitle)
+		return
+	}
+
+	if len(post.NewChatPhoto) > 0 || post.DeleteChatPhoto {
+		syncGroupMetadataByChatID(post.Chat.ID)
+	}
+}
@@ -0,0 +1,250 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// handlePrivateCommand answers member commands sent in a private chat.
+// Unlinked users get the generic warning message.
+func handlePrivateCommand(message *tgbotapi.Message) {
+	user, err := app.FindFirstRecordByFilter(
+		"users",
+		"telegram.id = {:id}",
+		map[string]any{"id": message.From.ID},
+	)
+	if err != nil || user == nil {
+		sendWarningMessage(message.Chat.ID)
+		return
+	}
+
+	if adminCommands[message.Command()] {
+		handleAdminCommand(message, user)
+		return
+	}
+
+	var reply string
+	switch message.Command() {
+	case "groups":
+		reply = groupsReply(user)
+	case "status":
+		reply = statusReply(user)
+	case "profile":
+		reply = profileReply(user)
+	case "unsubscribe", "subscribe":
+		optOut := message.Command() == "unsubscribe"
+		if err := setBroadcastOptOut(user, optOut); err != nil {
+			log.Printf("Failed to update broadcast subscription: %v", err)
+			return
+		}
+		reply = botMessage(message.Command()+"d", nil)
+	default:
+		reply = botMessage("help", nil)
+		if user.GetBool("admin") {
+			reply = strings.TrimSpace(reply + "\n\n" + adminHelp)
+		}
+	}
+
+	bot := currentBot()
+	if reply == "" || bot == nil {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, reply)
+	msg.DisableWebPagePreview = true
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("Failed to reply to /%s: %v", message.Command(), err)
+	}
+}
+
+func groupsReply(user *core.Record) string {
+	userGroupRecords, err := app.FindRecordsByFilter(
+		"user_groups",
+		"user = {:user}",
+		"",
+		0,
+		0,
+		map[string]any{"user": user.Id},
+	)
+	if err != nil || len(userGroupRecords) == 0 {
+		return botMessage("groups_empty", nil)
+	}
+
+	lines := []string{}
+	for _, ug := range userGroupRecords {
+		group, err := app.FindRecordById("groups", ug.GetString("group"))
+		if err != nil || !group.GetDateTime("disabled_at").IsZero() {
+			continue
+		}
+
+		line := "• " + group.GetString("name")
+		if ug.GetString("role") == "admin" {
+			line += " (admin)"
+		}
+		if inviteLink := group.GetString("invite_link"); inviteLink != "" {
+			line += "\n  " + inviteLink
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return botMessage("groups_empty", nil)
+	}
+
+	return botMessage("groups", map[string]string{
+		"groups": strings.Join(lines, "\n"),
+	})
+}
+
+func statusReply(user *core.Record) string {
+	request, err := app.FindFirstRecordByFilter(
+		"requests",
+		"email = {:email}",
+		map[string]any{"email": user.GetString("email")},
+	)
+	if err != nil || request == nil {
+		return botMessage("status_none", nil)
+	}
+
+	status := request.GetString("status")
+	if label := botMessageLabel("status_labels", status); label != "" {
+		status = label
+	}
+
+	groupName := "-"
+	if group, err := app.FindRecordById("groups", request.GetString("group")); err == nil {
+		groupName = group.GetString("name")
+	}
+
+	leaderApproved := "-"
+	adminConfirmed := "-"
+	guardian, err := app.FindFirstRecordByFilter(
+		"guardians",
+		"request = {:request}",
+		map[string]any{"request": request.Id},
+	)
+	if err == nil && guardian != nil {
+		if at := guardian.GetDateTime("leader_approved_at"); !at.IsZero() {
+			leaderApproved = "✅ " + at.Time().Format("2006-01-02")
+		} else {
+			leaderApproved = "⏳"
+		}
+		if at := guardian.GetDateTime("admin_confirmed_at"); !at.IsZero() {
+			adminConfirmed = "✅ " + at.Time().Format("2006-01-02")
+		} else {
+			adminConfirmed = "⏳"
+		}
+	}
+
+	return botMessage("status", map[string]string{
+		"status":          status,
+		"group":           groupName,
+		"leader_approved": leaderApproved,
+		"admin_confirmed": adminConfirmed,
+	})
+}
+
+func profileReply(user *core.Record) string {
+	var telegramData struct {
+		Username  string `json:"username"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+	user.UnmarshalJSONField("telegram", &telegramData)
+
+	telegramName := strings.TrimSpace(telegramData.FirstName + " " + telegramData.LastName)
+	if telegramData.Username != "" {
+		telegramName = "@" + telegramData.Username
+	}
+
+	name := user.GetString("name")
+	if name == "" {
+		name = user.GetString("email")
+	}
+
+	return botMessage("profile", map[string]string{
+		"name":        name,
+		"email":       user.GetString("email"),
+		"telegram":    telegramName,
+		"user_status": user.GetString("status"),
+	})
+}
+
+// loadBotMessages returns the data of the bot_messages setting.
+func loadBotMessages() (map[string]any, error) {
+	messagesRecord, err := app.FindFirstRecordByFilter(
+		"settings",
+		"name = 'bot_messages'",
+		map[string]any{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot messages settings: %w", err)
+	}
+
+	messagesData := map[string]any{}
+	if err := messagesRecord.UnmarshalJSONField("data", &messagesData); err != nil {
+		return nil, fmt.Errorf("failed to parse bot messages settings: %w", err)
+	}
+
+	return messagesData, nil
+}
+
+// botMessage returns a bot_messages text with {url} and the given placeholders replaced.
+func botMessage(key string, values map[string]string) string {
+	messagesData, err := loadBotMessages()
+	if err != nil {
+		log.Printf("%v", err)
+		return ""
+	}
+
+	text, _ := messagesData[key].(string)
+	if text == "" {
+		return ""
+	}
+
+	text = strings.ReplaceAll(text, "{url}", appURL())
+	for placeholder, value := range values {
+		text = strings.ReplaceAll(text, "{"+placeholder+"}", value)
+	}
+
+	return text
+}
+
+// botMessageLabel returns a label from a bot_messages map (e.g. status_labels).
+func botMessageLabel(key string, value string) string {
+	messagesData, err := loadBotMessages()
+	if err != nil {
+		return ""
+	}
+
+	labels, _ := messagesData[key].(map[string]any)
+	label, _ := labels[value].(string)
+	return label
+}
+
+// appURL returns the public address from the url setting.
+func appURL() string {
+	address := "http://localhost:8090"
+
+	urlRecord, err := app.FindFirstRecordByFilter(
+		"settings",
+		"name = 'url'",
+		map[string]any{},
+	)
+	if err != nil {
+		return address
+	}
+
+	var urlData struct {
+		Address string `json:"address"`
+	}
+	if err := urlRecord.UnmarshalJSONField("data", &urlData); err == nil && urlData.Address != "" {
+		address = urlData.Address
+	}
+
+	return address
+}
@@ -0,0 +1,306 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Conflict resolutions accepted by ResolveTelegramConflict.
+const (
+	ConflictKeepExisting = "keep_existing"
+	ConflictTransfer     = "transfer"
+	ConflictMerge        = "merge"
+)
+
+// openTelegramConflict queues a Telegram account clash for the admins.
+func openTelegramConflict(existing *core.Record, requested *core.Record, from *tgbotapi.User) {
+	conflict, err := app.FindFirstRecordByFilter(
+		"telegram_conflicts",
+		"telegram_id = {:id} && existing_user = {:existing} && requested_user = {:requested} && status = 'open'",
+		map[string]any{
+			"id":        from.ID,
+			"existing":  existing.Id,
+			"requested": requested.Id,
+		},
+	)
+	if err == nil && conflict != nil {
+		return
+	}
+
+	collection, err := app.FindCollectionByNameOrId("telegram_conflicts")
+	if err != nil {
+		log.Printf("Failed to find telegram_conflicts collection: %v", err)
+		return
+	}
+
+	conflict = core.NewRecord(collection)
+	conflict.Set("telegram_id", from.ID)
+	conflict.Set("telegram", map[string]any{
+		"id":         from.ID,
+		"username":   from.UserName,
+		"first_name": from.FirstName,
+		"last_name":  from.LastName,
+	})
+	conflict.Set("existing_user", existing.Id)
+	conflict.Set("requested_user", requested.Id)
+	conflict.Set("status", "open")
+
+	if err := app.Save(conflict); err != nil {
+		log.Printf("Failed to save telegram conflict: %v", err)
+		return
+	}
+
+	notifyAdmins(fmt.Sprintf(
+		"⚠️ Telegram account conflict\n\nTelegram ID %d is linked to %s and was requested by %s.\n\nPlease resolve it from the conflict queue (ID: %s).",
+		from.ID,
+		existing.GetString("email"),
+		requested.GetString("email"),
+		conflict.Id,
+	))
+}
+
+// ResolveTelegramConflict applies an admin decision to an open conflict:
+//   - keep_existing leaves the Telegram account with its current user
+//   - transfer moves the Telegram account to the requesting user
+//   - merge also moves memberships, guardianships and leaderships, then suspends the old user
+func ResolveTelegramConflict(conflict *core.Record, action string, admin *core.Record) error {
+	if app == nil {
+		return fmt.Errorf("telegram bot not started")
+	}
+	if conflict.GetString("status") != "open" {
+		return fmt.Errorf("conflict already resolved")
+	}
+
+	existing, err := app.FindRecordById("users", conflict.GetString("existing_user"))
+	if err != nil {
+		return err
+	}
+	requested, err := app.FindRecordById("users", conflict.GetString("requested_user"))
+	if err != nil {
+		return err
+	}
+
+	var identity struct {
+		ID        int64  `json:"id"`
+		Username  string `json:"username"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+	if err := conflict.UnmarshalJSONField("telegram", &identity); err != nil || identity.ID == 0 {
+		identity.ID = int64(conflict.GetInt("telegram_id"))
+	}
+
+	switch action {
+	case ConflictKeepExisting:
+		sendDirectMessage(identity.ID, fmt.Sprintf(
+			"ℹ️ An admin reviewed your request. This Telegram account stays connected to %s.",
+			existing.GetString("email"),
+		))
+	case ConflictTransfer, ConflictMerge:
+		from := &tgbotapi.User{
+			ID:        identity.ID,
+			UserName:  identity.Username,
+			FirstName: identity.FirstName,
+			LastName:  identity.LastName,
+		}
+
+		// The identity moves in one transaction, so a failed link leaves it
+		// with the existing user and the conflict open
+		var previousID int64
+		err := app.RunInTransaction(func(txApp core.App) error {
+			if action == ConflictMerge {
+				if err := mergeUserRecords(txApp, existing, requested); err != nil {
+					return err
+				}
+				existing.Set("status", "suspended")
+			} else {
+				userGroupRecords, err := txApp.FindRecordsByFilter(
+					"user_groups",
+					"user = {:user}",
+					"",
+					0,
+					0,
+					map[string]any{"user": existing.Id},
+				)
+				if err != nil {
+					return err
+				}
+				for _, ug := range userGroupRecords {
+					if err := txApp.Delete(ug); err != nil {
+						return err
+					}
+				}
+			}
+
+			existing.Set("telegram", nil)
+			if err := txApp.Save(existing); err != nil {
+				return err
+			}
+
+			previousID, _, err = saveTelegramIdentity(txApp, requested, from)
+			if err != nil {
+				return err
+			}
+
+			return saveConflictResolution(txApp, conflict, action, admin)
+		})
+		if err != nil {
+			return err
+		}
+
+		completeTelegramLink(requested, from, previousID)
+		ensureTelegramIDIndex()
+
+		sendDirectMessage(identity.ID, fmt.Sprintf(
+			"✅ An admin reviewed your request. This Telegram account is now connected to %s.",
+			requested.GetString("email"),
+		))
+
+		log.Printf("✓ Resolved telegram conflict %s with '%s'", conflict.Id, action)
+
+		return nil
+	default:
+		return fmt.Errorf("unknown resolution %q", action)
+	}
+
+	if err := saveConflictResolution(app, conflict, action, admin); err != nil {
+		return err
+	}
+	ensureTelegramIDIndex()
+
+	log.Printf("✓ Resolved telegram conflict %s with '%s'", conflict.Id, action)
+
+	return nil
+}
+
+// telegramIDIndex makes the database reject a second user with the same
+// Telegram ID. Users linked twice before the rule block it, so it is added
+// once their last conflict is resolved.
+const telegramIDIndex = "idx_users_telegram_id"
+
+// ensureTelegramIDIndex adds telegramIDIndex to users when it is missing and
+// no Telegram ID is linked to more than one user.
+func ensureTelegramIDIndex() {
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil || users.GetIndex(telegramIDIndex) != "" {
+		return
+	}
+
+	var duplicates []struct {
+		TelegramID int64 `db:"telegram_id"`
+	}
+	err = app.DB().NewQuery(
+		"SELECT json_extract([[telegram]], '$.id') AS [[telegram_id]] FROM {{users}} " +
+			"WHERE json_valid([[telegram]]) AND json_extract([[telegram]], '$.id') IS NOT NULL " +
+			"GROUP BY [[telegram_id]] HAVING COUNT(*) > 1",
+	).All(&duplicates)
+	if err != nil || len(duplicates) > 0 {
+		return
+	}
+
+	users.AddIndex(
+		telegramIDIndex,
+		true,
+		// NULL for unlinked users, NULLs do not collide
+		"(CASE WHEN json_valid(`telegram`) THEN json_extract(`telegram`, '$.id') END)",
+		"",
+	)
+	if err := app.Save(users); err != nil {
+		log.Printf("Failed to add the unique Telegram ID index: %v", err)
+		return
+	}
+
+	log.Printf("✓ Added the unique Telegram ID index, no duplicates left")
+}
+
+// saveConflictResolution marks the conflict as resolved with the action.
+func saveConflictResolution(txApp core.App, conflict *core.Record, action string, admin *core.Record) error {
+	conflict.Set("status", "resolved")
+	conflict.Set("resolution", action)
+	conflict.Set("resolved_at", types.NowDateTime())
+	if admin != nil {
+		conflict.Set("resolved_by", admin.Id)
+	}
+	return txApp.Save(conflict)
+}
+
+// mergeUserRecords moves memberships, guardianships and group leaderships from one user to another.
+func mergeUserRecords(txApp core.App, from *core.Record, to *core.Record) error {
+	userGroupRecords, err := txApp.FindRecordsByFilter(
+		"user_groups",
+		"user = {:user}",
+		"",
+		0,
+		0,
+		map[string]any{"user": from.Id},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, ug := range userGroupRecords {
+		existingRecord, _ := txApp.FindFirstRecordByFilter(
+			"user_groups",
+			"user = {:user} && group = {:group}",
+			map[string]any{
+				"user":  to.Id,
+				"group": ug.GetString("group"),
+			},
+		)
+		if existingRecord != nil {
+			if err := txApp.Delete(ug); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ug.Set("user", to.Id)
+		if err := txApp.Save(ug); err != nil {
+			return err
+		}
+	}
+
+	guardianRecords, err := txApp.FindRecordsByFilter(
+		"guardians",
+		"guardian = {:user}",
+		"",
+		0,
+		0,
+		map[string]any{"user": from.Id},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, guardian := range guardianRecords {
+		guardian.Set("guardian", to.Id)
+		if err := txApp.Save(guardian); err != nil {
+			return err
+		}
+	}
+
+	groups, err := txApp.FindRecordsByFilter(
+		"groups",
+		"leader = {:user}",
+		"",
+		0,
+		0,
+		map[string]any{"user": from.Id},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		group.Set("leader", to.Id)
+		if err := txApp.Save(group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// BindGateJob registers the cron job that removes restricted users after the group timeout.
+func BindGateJob(pbApp *pocketbase.PocketBase) {
+	pbApp.Cron().MustAdd("gate_timeouts", "* * * * *", func() {
+		if currentBot() == nil {
+			return
+		}
+		runTask("gate timeouts", processGateTimeouts)
+	})
+}
+
+// restrictUnknownJoiner makes an unregistered user read-only in groups with
+// gate_mode "restrict" and posts the signup link in the group.
+func restrictUnknownJoiner(update *tgbotapi.ChatMemberUpdated, sighting *core.Record) {
+	if sighting == nil || update.NewChatMember.Status != "member" || isInChatStatus(update.OldChatMember.Status) {
+		return
+	}
+
+	group, err := findGroupByChatID(update.Chat.ID)
+	if err != nil || group.GetString("gate_mode") != "restrict" || group.GetString("kind") == "channel" {
+		return
+	}
+
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
+
+	from := update.NewChatMember.User
+	_, err = bot.Request(tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{
+			ChatID: update.Chat.ID,
+			UserID: from.ID,
+		},
+		Permissions: &tgbotapi.ChatPermissions{},
+	})
+	if err != nil {
+		log.Printf("Failed to restrict Telegram ID %d in '%s': %v", from.ID, group.GetString("name"), err)
+		return
+	}
+
+	sighting.Set("restricted_at", types.NowDateTime())
+	if err := app.Save(sighting); err != nil {
+		log.Printf("Failed to save restricted_at: %v", err)
+	}
+
+	log.Printf("✓ Restricted unregistered Telegram ID %d in '%s'", from.ID, group.GetString("name"))
+
+	name := strings.TrimSpace(from.FirstName + " " + from.LastName)
+	if from.UserName != "" {
+		name = "@" + from.UserName
+	}
+
+	text := botMessage("gate_restricted", map[string]string{"name": name})
+	if text == "" {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(update.Chat.ID, text)
+	msg.DisableWebPagePreview = true
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("Failed to send gate message: %v", err)
+	}
+}
+
+// liftRestriction gives a restricted user the default permissions of the chat back.
+func liftRestriction(chatID int64, telegramID int64) {
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
+
+	permissions := &tgbotapi.ChatPermissions{
+		CanSendMessages:       true,
+		CanSendMediaMessages:  true,
+		CanSendPolls:          true,
+		CanSendOtherMessages:  true,
+		CanAddWebPagePreviews: true,
+		CanInviteUsers:        true,
+	}
+	if chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}}); err == nil && chat.Permissions != nil {
+		permissions = chat.Permissions
+	}
+
+	_, err := bot.Request(tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{
+			ChatID: chatID,
+			UserID: telegramID,
+		},
+		Permissions: permissions,
+	})
+	if err != nil {
+		log.Printf("Failed to lift restriction of Telegram ID %d in chat %d: %v", telegramID, chatID, err)
+		return
+	}
+
+	log.Printf("✓ Lifted restriction of Telegram ID %d in chat %d", telegramID, chatID)
+}
+
+// processGateTimeouts removes restricted users whose group timeout passed.
+// Restrictions in groups whose gate was turned off are lifted.
+func processGateTimeouts(ctx context.Context) {
+	sightings, err := app.FindRecordsByFilter(
+		"telegram_sightings",
+		"restricted_at != ''",
+		"restricted_at",
+		0,
+		0,
+	)
+	if err != nil {
+		return
+	}
+
+	for _, sighting := range sightings {
+		if ctx.Err() != nil {
+			return
+		}
+
+		group, err := app.FindRecordById("groups", sighting.GetString("group"))
+		if err != nil {
+			continue
+		}
+
+		// The group has the current chat ID, also after a supergroup migration
+		chatID := groupChatID(group)
+		if chatID == 0 {
+			continue
+		}
+		telegramID := int64(sighting.GetInt("telegram_id"))
+
+		if group.GetString("gate_mode") != "restrict" {
+			liftRestriction(chatID, telegramID)
+			sighting.Set("restricted_at", nil)
+			if err := app.Save(sighting); err != nil {
+				log.Printf("Failed to save Telegram sighting: %v", err)
+			}
+			continue
+		}
+
+		timeout := group.GetInt("gate_timeout")
+		if timeout <= 0 {
+			continue
+		}
+
+		deadline := sighting.GetDateTime("restricted_at").Time().Add(time.Duration(timeout) * time.Minute)
+		if time.Now().Before(deadline) {
+			continue
+		}
+
+		if err := removeFromChat(chatID, telegramID); err != nil {
+			log.Printf("Failed to remove Telegram ID %d from '%s': %v", telegramID, group.GetString("name"), err)
+			continue
+		}
+
+		sighting.Set("status", "kicked")
+		sighting.Set("restricted_at", nil)
+		if err := app.Save(sighting); err != nil {
+			log.Printf("Failed to save Telegram sighting: %v", err)
+		}
+
+		log.Printf("✓ Removed restricted Telegram ID %d from '%s' after %d minutes", telegramID, group.GetString("name"), timeout)
+	}
+}
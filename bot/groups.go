@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// BindGroupSyncJob registers the cron job that refreshes group metadata from Telegram.
+func BindGroupSyncJob(pbApp *pocketbase.PocketBase) {
+	pbApp.Cron().MustAdd("groups_sync", "*/30 * * * *", func() {
+		if currentBot() == nil {
+			return
+		}
+		runTask("groups metadata sync", syncGroupsMetadata)
+	})
+}
+
+// syncGroupsMetadata refreshes every Telegram group from the Bot API.
+func syncGroupsMetadata(ctx context.Context) {
+	groups, err := app.FindRecordsByFilter("groups", "type = 'telegram' && disabled_at = ''", "", 0, 0)
+	if err != nil {
+		return
+	}
+
+	for _, group := range groups {
+		if ctx.Err() != nil {
+			return
+		}
+		syncGroupMetadata(group)
+	}
+
+	recordSyncRun()
+}
+
+func syncGroupMetadataByChatID(chatID int64) {
+	group, err := app.FindFirstRecordByFilter(
+		"groups",
+		"telegram.chat_id = {:id}",
+		map[string]any{"id": fmt.Sprintf("%d", chatID)},
+	)
+	if err != nil {
+		return
+	}
+
+	syncGroupMetadata(group)
+}
+
+// syncGroupMetadata copies title, description, username, primary invite link,
+// member count and photo of the Telegram chat to the group record.
+func syncGroupMetadata(group *core.Record) {
+	chatID := groupChatID(group)
+	bot := currentBot()
+	if chatID == 0 || bot == nil {
+		return
+	}
+
+	chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+	})
+	if err != nil {
+		var tgErr *tgbotapi.Error
+		if errors.As(err, &tgErr) && tgErr.MigrateToChatID != 0 {
+			migrateGroupChat(chatID, tgErr.MigrateToChatID)
+			return
+		}
+		log.Printf("Failed to get chat %d: %v", chatID, err)
+		return
+	}
+
+	if chat.Title != "" {
+		group.Set("name", chat.Title)
+	}
+	group.Set("description", chat.Description)
+	group.Set("username", chat.UserName)
+
+	// getChat only returns the primary link when the bot is admin
+	if chat.InviteLink != "" {
+		group.Set("invite_link", chat.InviteLink)
+	}
+
+	count, err := bot.GetChatMembersCount(tgbotapi.ChatMemberCountConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+	})
+	if err == nil {
+		group.Set("member_count", count)
+	}
+
+	if chat.Photo == nil {
+		group.Set("photo", nil)
+		group.Set("photo_id", "")
+	} else if chat.Photo.BigFileUniqueID != group.GetString("photo_id") {
+		if photo, err := downloadChatPhoto(bot, chat.Photo.BigFileID); err != nil {
+			log.Printf("Failed to download photo of chat %d: %v", chatID, err)
+		} else {
+			group.Set("photo", photo)
+			group.Set("photo_id", chat.Photo.BigFileUniqueID)
+		}
+	}
+
+	if err := app.Save(group); err != nil {
+		log.Printf("Failed to sync group metadata: %v", err)
+		return
+	}
+
+	log.Printf("✓ Synced group metadata for '%s' (ID: %d)", group.GetString("name"), chatID)
+}
+
+func downloadChatPhoto(bot *tgbotapi.BotAPI, fileID string) (*filesystem.File, error) {
+	url, err := bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return filesystem.NewFileFromURL(context.Background(), url)
+}
+
+// migrateGroupChat moves a group to the chat ID of the supergroup it was upgraded to.
+// Leader, regions, is_open and user_groups stay on the existing record.
+func migrateGroupChat(oldChatID int64, newChatID int64) {
+	group, err := app.FindFirstRecordByFilter(
+		"groups",
+		"telegram.chat_id = {:id}",
+		map[string]any{"id": fmt.Sprintf("%d", oldChatID)},
+	)
+	if err != nil {
+		return
+	}
+
+	err = app.RunInTransaction(func(txApp core.App) error {
+		// The bot may already have registered the new supergroup as a separate group
+		duplicate, _ := txApp.FindFirstRecordByFilter(
+			"groups",
+			"telegram.chat_id = {:id} && id != {:group}",
+			map[string]any{
+				"id":    fmt.Sprintf("%d", newChatID),
+				"group": group.Id,
+			},
+		)
+		if duplicate != nil {
+			if err := moveUserGroups(txApp, duplicate, group); err != nil {
+				return err
+			}
+			if err := txApp.Delete(duplicate); err != nil {
+				return err
+			}
+		}
+
+		telegramData := map[string]any{}
+		group.UnmarshalJSONField("telegram", &telegramData)
+		telegramData["chat_id"] = fmt.Sprintf("%d", newChatID)
+		telegramData["type"] = "supergroup"
+		group.Set("telegram", telegramData)
+
+		return txApp.Save(group)
+	})
+	if err != nil {
+		log.Printf("Failed to migrate group '%s' to supergroup: %v", group.GetString("name"), err)
+		return
+	}
+
+	log.Printf("✓ Migrated group '%s' from chat %d to supergroup %d", group.GetString("name"), oldChatID, newChatID)
+}
+
+// moveUserGroups moves the memberships of one group to another, skipping users already in it.
+func moveUserGroups(txApp core.App, from *core.Record, to *core.Record) error {
+	userGroupRecords, err := txApp.FindRecordsByFilter(
+		"user_groups",
+		"group = {:group}",
+		"",
+		0,
+		0,
+		map[string]any{"group": from.Id},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, ug := range userGroupRecords {
+		existingRecord, _ := txApp.FindFirstRecordByFilter(
+			"user_groups",
+			"user = {:user} && group = {:group}",
+			map[string]any{
+				"user":  ug.GetString("user"),
+				"group": to.Id,
+			},
+		)
+		if existingRecord != nil {
+			if err := txApp.Delete(ug); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ug.Set("group", to.Id)
+		if err := txApp.Save(ug); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
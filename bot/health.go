@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+)
+
+// health records what the status endpoint reports about the bot.
+var health = struct {
+	sync.Mutex
+	username     string
+	startError   string
+	lastUpdateAt time.Time
+	lastSyncAt   time.Time
+}{}
+
+// Status describes the state of the Telegram bot on this instance.
+type Status struct {
+	Username      string     `json:"username"`
+	Active        bool       `json:"active"`
+	StartError    string     `json:"start_error,omitempty"`
+	LastUpdateAt  *time.Time `json:"last_update_at"`
+	LastSyncAt    *time.Time `json:"last_sync_at"`
+	PendingOutbox int        `json:"pending_outbox"`
+	Lock          LockStatus `json:"lock"`
+}
+
+func recordStart(username string, err error) {
+	health.Lock()
+	defer health.Unlock()
+
+	health.username = username
+	health.startError = ""
+	if err != nil {
+		health.startError = err.Error()
+	}
+}
+
+func recordUpdateReceived() {
+	health.Lock()
+	health.lastUpdateAt = time.Now()
+	health.Unlock()
+}
+
+func recordSyncRun() {
+	health.Lock()
+	health.lastSyncAt = time.Now()
+	health.Unlock()
+}
+
+// BotStatus returns the bot state and the number of broadcast deliveries
+// waiting to be sent.
+func BotStatus() Status {
+	health.Lock()
+	status := Status{
+		Username:   health.username,
+		Active:     active.Load(),
+		StartError: health.startError,
+	}
+	if !health.lastUpdateAt.IsZero() {
+		at := health.lastUpdateAt
+		status.LastUpdateAt = &at
+	}
+	if !health.lastSyncAt.IsZero() {
+		at := health.lastSyncAt
+		status.LastSyncAt = &at
+	}
+	health.Unlock()
+
+	if app == nil {
+		return status
+	}
+
+	if pending, err := app.CountRecords("broadcast_deliveries", dbx.HashExp{"status": "pending"}); err == nil {
+		status.PendingOutbox = int(pending)
+	}
+
+	status.Lock, _ = BotLockStatus()
+
+	return status
+}
@@ -0,0 +1,332 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	// leaseDuration is how long a lease stays valid without renewal.
+	leaseDuration = 30 * time.Second
+	// leaseRenewInterval is how often the holder renews and standbys poll.
+	leaseRenewInterval = 10 * time.Second
+)
+
+// instanceID identifies this process in the bot lock.
+var instanceID = newInstanceID()
+
+// active is true while this instance holds the lock and processes updates.
+var active atomic.Bool
+
+// lease controls the lease loop started by StartTelegramBot.
+var lease struct {
+	sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// lockState is the data of the bot_state "lock" record.
+type lockState struct {
+	Holder     string `json:"holder"`
+	ExpiresAt  string `json:"expires_at"`
+	ConflictAt string `json:"conflict_at,omitempty"`
+}
+
+// LockStatus describes the bot lock as seen by this instance.
+type LockStatus struct {
+	Instance   string `json:"instance"`
+	Active     bool   `json:"active"`
+	Holder     string `json:"holder"`
+	ExpiresAt  string `json:"expires_at"`
+	ConflictAt string `json:"conflict_at,omitempty"`
+}
+
+func newInstanceID() string {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "unknown"
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// acquireLease takes or renews the bot lock. It returns false while another
+// instance holds an unexpired lease.
+func acquireLease() (bool, error) {
+	acquired := false
+
+	err := app.RunInTransaction(func(txApp core.App) error {
+		record, _ := txApp.FindFirstRecordByFilter(
+			"bot_state",
+			"name = 'lock'",
+			map[string]any{},
+		)
+
+		var state lockState
+		if record == nil {
+			collection, err := txApp.FindCollectionByNameOrId("bot_state")
+			if err != nil {
+				return err
+			}
+			record = core.NewRecord(collection)
+			record.Set("name", "lock")
+		} else {
+			record.UnmarshalJSONField("data", &state)
+		}
+
+		now := time.Now()
+		if state.Holder != "" && state.Holder != instanceID {
+			expiresAt, err := types.ParseDateTime(state.ExpiresAt)
+			if err == nil && expiresAt.Time().After(now) {
+				return nil
+			}
+		}
+
+		if state.Holder != instanceID {
+			state.ConflictAt = ""
+		}
+		state.Holder = instanceID
+		state.ExpiresAt = types.NowDateTime().Add(leaseDuration).String()
+
+		record.Set("data", state)
+		if err := txApp.Save(record); err != nil {
+			return err
+		}
+
+		acquired = true
+		return nil
+	})
+
+	return acquired, err
+}
+
+// releaseLease gives up the lock so a standby instance can take over at once.
+func releaseLease() {
+	err := app.RunInTransaction(func(txApp core.App) error {
+		record, err := txApp.FindFirstRecordByFilter(
+			"bot_state",
+			"name = 'lock'",
+			map[string]any{},
+		)
+		if err != nil {
+			return nil
+		}
+
+		var state lockState
+		record.UnmarshalJSONField("data", &state)
+		if state.Holder != instanceID {
+			return nil
+		}
+
+		record.Set("data", lockState{})
+		return txApp.Save(record)
+	})
+	if err != nil {
+		log.Printf("Failed to release bot lock: %v", err)
+	}
+}
+
+// recordLockConflict stores when getUpdates reported another poller for the token.
+func recordLockConflict() {
+	var state lockState
+	if err := loadBotState("lock", &state); err != nil || state.Holder != instanceID {
+		return
+	}
+
+	state.ConflictAt = types.NowDateTime().String()
+	if err := saveBotState("lock", state); err != nil {
+		log.Printf("Failed to save bot lock conflict: %v", err)
+	}
+}
+
+// runLease keeps the lease of the active instance and lets standby instances
+// take over when it expires.
+func runLease(api *tgbotapi.BotAPI, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(leaseRenewInterval)
+	defer ticker.Stop()
+
+	standby := false
+	// cleared is closed once the client of the last activation is released
+	var cleared <-chan struct{}
+	// lastRenew is when this instance last held the lease
+	var lastRenew time.Time
+	for {
+		acquired, err := acquireLease()
+		if err != nil {
+			log.Printf("Failed to renew bot lock: %v", err)
+		}
+		if acquired {
+			lastRenew = time.Now()
+		}
+
+		switch {
+		case acquired && !active.Load() && isClosed(cleared):
+			standby = false
+			activateBot(api)
+		case !acquired && err == nil && active.Load():
+			log.Printf("Bot lock taken over by another instance")
+			cleared = deactivateBot()
+		case !acquired && active.Load() && time.Since(lastRenew)+leaseRenewInterval > leaseDuration:
+			// Renewals keep failing: stop before the lease can expire and a
+			// standby starts polling too
+			log.Printf("Bot lock could not be renewed, stopping before it expires")
+			cleared = deactivateBot()
+		case !acquired && !active.Load() && !standby:
+			standby = true
+			log.Printf("Telegram bot standing by, another instance holds the lock")
+		}
+
+		select {
+		case <-stop:
+			if active.Load() {
+				deactivateBot()
+			}
+			releaseLease()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isClosed reports whether ch is nil or closed.
+func isClosed(ch <-chan struct{}) bool {
+	if ch == nil {
+		return true
+	}
+
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// activateBot starts update processing once this instance holds the lock.
+func activateBot(api *tgbotapi.BotAPI) {
+	client.Store(api)
+	active.Store(true)
+	startTasks()
+
+	// Start listening for updates
+	goTask("updates", listenForUpdates)
+	goTask("membership sync", runSyncWorker)
+	// Catch-up for group metadata when the app was offline.
+	goTask("groups metadata sync", syncGroupsMetadata)
+
+	log.Printf("✓ Telegram bot active on instance %s", instanceID)
+}
+
+// deactivateBot stops update processing and waits for the in-flight work.
+// The client is cleared only once every task has finished, tasks that outlive
+// the drain timeout keep using it. The returned channel is closed then.
+func deactivateBot() <-chan struct{} {
+	active.Store(false)
+	finished := stopTasks()
+
+	cleared := make(chan struct{})
+	release := func() {
+		client.Store(nil)
+		log.Printf("Telegram bot inactive on instance %s", instanceID)
+		close(cleared)
+	}
+
+	if isClosed(finished) {
+		release()
+	} else {
+		go func() {
+			<-finished
+			release()
+		}()
+	}
+
+	return cleared
+}
+
+// pollUpdates long-polls getUpdates until ctx is cancelled.
+// Conflicts (HTTP 409) mean another process polls with the same token.
+func pollUpdates(ctx context.Context, api *tgbotapi.BotAPI, config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	ch := make(chan tgbotapi.Update, api.Buffer)
+
+	go func() {
+		defer close(ch)
+
+		conflict := false
+		for ctx.Err() == nil {
+			updates, err := api.GetUpdates(config)
+			if err != nil {
+				var tgErr *tgbotapi.Error
+				if errors.As(err, &tgErr) && tgErr.Code == http.StatusConflict {
+					if !conflict {
+						conflict = true
+						log.Printf("getUpdates conflict: another process is polling with this bot token")
+						recordLockConflict()
+						notifyAdmins("⚠️ Another process is receiving updates with this bot token. Only one instance can run the bot.")
+					}
+				} else {
+					log.Printf("Failed to get updates: %v", err)
+				}
+				telegramRetriesTotal.Inc("get_updates")
+				sleepContext(ctx, 3*time.Second)
+				continue
+			}
+			conflict = false
+
+			for _, update := range updates {
+				if update.UpdateID < config.Offset {
+					continue
+				}
+				config.Offset = update.UpdateID + 1
+
+				select {
+				case ch <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch
+}
+
+// BotLockStatus returns the current holder of the bot lock.
+func BotLockStatus() (LockStatus, error) {
+	status := LockStatus{
+		Instance: instanceID,
+		Active:   active.Load(),
+	}
+
+	if app == nil {
+		return status, fmt.Errorf("telegram bot not started")
+	}
+
+	var state lockState
+	if err := loadBotState("lock", &state); err != nil {
+		return status, err
+	}
+
+	status.Holder = state.Holder
+	status.ExpiresAt = state.ExpiresAt
+	status.ConflictAt = state.ConflictAt
+
+	return status, nil
+}
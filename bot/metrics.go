@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"net/http"
+	"path"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"members/metrics"
+)
+
+var (
+	updatesTotal         = metrics.NewCounter("members_bot_updates_total", "Telegram updates handled by type.", "type")
+	telegramErrorsTotal  = metrics.NewCounter("members_telegram_api_errors_total", "Failed Telegram Bot API calls by method.", "method")
+	telegramRetriesTotal = metrics.NewCounter("members_telegram_api_retries_total", "Retried Telegram Bot API calls by reason.", "reason")
+)
+
+// updateType names the kind of an update for metrics.
+func updateType(update tgbotapi.Update) string {
+	switch {
+	case update.MyChatMember != nil:
+		return "my_chat_member"
+	case update.ChatMember != nil:
+		return "chat_member"
+	case update.ChatJoinRequest != nil:
+		return "chat_join_request"
+	case update.ChannelPost != nil:
+		return "channel_post"
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.Message != nil:
+		return "message"
+	default:
+		return "other"
+	}
+}
+
+// metricsTransport counts failed Bot API calls by method.
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode >= 400 {
+		telegramErrorsTotal.Inc(method)
+	}
+
+	return resp, err
+}
+
+// newBotAPI creates the Bot API client with instrumented HTTP calls.
+func newBotAPI(token string) (*tgbotapi.BotAPI, error) {
+	client := &http.Client{
+		Transport: metricsTransport{next: http.DefaultTransport},
+	}
+
+	return tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, client)
+}
@@ -0,0 +1,311 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	reminderStageRemind   = "remind"
+	reminderStageEscalate = "escalate"
+)
+
+// slaRule holds the thresholds of one request status or guardian step.
+type slaRule struct {
+	RemindAfterHours   int `json:"remind_after_hours"`
+	EscalateAfterHours int `json:"escalate_after_hours"`
+}
+
+// slaSettings is the data of the sla setting.
+type slaSettings struct {
+	RepeatHours int                `json:"repeat_hours"`
+	Rules       map[string]slaRule `json:"rules"`
+}
+
+// staleItem is a request or guardian record waiting on someone.
+type staleItem struct {
+	collection string
+	record     *core.Record
+	rule       string
+	since      time.Time
+	leader     *core.Record
+	summary    string
+}
+
+var reminderStatuses = []string{"1-accepted", "2-assigned"}
+
+// BindReminderJob registers the hourly cron job that sends SLA reminders.
+func BindReminderJob(pbApp *pocketbase.PocketBase) {
+	pbApp.Cron().MustAdd("sla_reminders", "15 * * * *", func() {
+		// The active instance sends reminders, standby instances skip
+		if isStandby() {
+			return
+		}
+		// Without Telegram the reminders still go out by email
+		if currentBot() == nil {
+			processReminders(context.Background())
+			return
+		}
+		runTask("sla reminders", processReminders)
+	})
+}
+
+// isStandby reports whether another instance holds the bot lock.
+func isStandby() bool {
+	lease.Lock()
+	defer lease.Unlock()
+
+	return lease.stop != nil && !active.Load()
+}
+
+func loadSLASettings() (slaSettings, error) {
+	settings := slaSettings{RepeatHours: 24}
+
+	record, err := app.FindFirstRecordByFilter(
+		"settings",
+		"name = 'sla'",
+		map[string]any{},
+	)
+	if err != nil {
+		return settings, fmt.Errorf("sla settings not found: %w", err)
+	}
+
+	if err := record.UnmarshalJSONField("data", &settings); err != nil {
+		return settings, fmt.Errorf("failed to parse sla settings: %w", err)
+	}
+
+	if settings.RepeatHours <= 0 {
+		settings.RepeatHours = 24
+	}
+
+	return settings, nil
+}
+
+// processReminders reminds the responsible leader of stale requests and
+// guardian records, and escalates to the admins after the second threshold.
+func processReminders(ctx context.Context) {
+	if app == nil {
+		return
+	}
+
+	settings, err := loadSLASettings()
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+
+	admins, err := app.FindRecordsByFilter("users", "admin = true", "", 0, 0)
+	if err != nil {
+		log.Printf("Failed to load admins: %v", err)
+		return
+	}
+
+	repeat := time.Duration(settings.RepeatHours) * time.Hour
+
+	for _, item := range staleItems(settings) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		rule := settings.Rules[item.rule]
+		age := time.Since(item.since)
+
+		if rule.RemindAfterHours > 0 && age >= time.Duration(rule.RemindAfterHours)*time.Hour {
+			recipients := admins
+			if item.leader != nil && item.rule != "admin_confirmation" {
+				recipients = []*core.Record{item.leader}
+			}
+			for _, recipient := range recipients {
+				sendReminder(item, reminderStageRemind, recipient, repeat)
+			}
+		}
+
+		if rule.EscalateAfterHours > 0 && age >= time.Duration(rule.EscalateAfterHours)*time.Hour {
+			for _, admin := range admins {
+				sendReminder(item, reminderStageEscalate, admin, repeat)
+			}
+		}
+	}
+}
+
+// staleItems returns the requests and guardian records covered by an SLA rule.
+func staleItems(settings slaSettings) []staleItem {
+	items := []staleItem{}
+
+	for _, status := range reminderStatuses {
+		if _, ok := settings.Rules[status]; !ok {
+			continue
+		}
+
+		requests, err := app.FindRecordsByFilter(
+			"requests",
+			"status = {:status}",
+			"",
+			0,
+			0,
+			map[string]any{"status": status},
+		)
+		if err != nil {
+			continue
+		}
+
+		for _, request := range requests {
+			since := request.GetDateTime("status_changed_at")
+			if since.IsZero() {
+				since = request.GetDateTime("updated")
+			}
+
+			groupName := "-"
+			var leader *core.Record
+			if group, err := app.FindRecordById("groups", request.GetString("group")); err == nil {
+				groupName = group.GetString("name")
+				leader, _ = app.FindRecordById("users", group.GetString("leader"))
+			}
+
+			items = append(items, staleItem{
+				collection: "requests",
+				record:     request,
+				rule:       status,
+				since:      since.Time(),
+				leader:     leader,
+				summary: fmt.Sprintf(
+					"Request of %s (%s) in %s is '%s'",
+					request.GetString("name"),
+					request.GetString("email"),
+					groupName,
+					status,
+				),
+			})
+		}
+	}
+
+	guardians, err := app.FindRecordsByFilter("guardians", "admin_confirmed_at = ''", "", 0, 0)
+	if err != nil {
+		return items
+	}
+
+	for _, record := range guardians {
+		group, err := app.FindRecordById("groups", record.GetString("group"))
+		if err != nil {
+			continue
+		}
+
+		item := staleItem{
+			collection: "guardians",
+			record:     record,
+			summary:    guardianSummary(record, group),
+		}
+
+		switch {
+		case record.GetDateTime("leader_approved_at").IsZero():
+			if !guardianStepsDone(record) {
+				continue
+			}
+			item.rule = "leader_approval"
+			item.since = record.GetDateTime("updated").Time()
+			if notifiedAt := record.GetDateTime("leader_notified_at"); !notifiedAt.IsZero() {
+				item.since = notifiedAt.Time()
+			}
+			item.leader, _ = app.FindRecordById("users", group.GetString("leader"))
+		default:
+			item.rule = "admin_confirmation"
+			item.since = record.GetDateTime("leader_approved_at").Time()
+		}
+
+		if _, ok := settings.Rules[item.rule]; ok {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// sendReminder notifies one recipient unless they got the same reminder
+// within the repeat period, and records it.
+func sendReminder(item staleItem, stage string, recipient *core.Record, repeat time.Duration) {
+	previous, _ := app.FindFirstRecordByFilter(
+		"reminders",
+		"target = {:target} && rule = {:rule} && stage = {:stage} && recipient = {:recipient} && sent_at > {:since}",
+		map[string]any{
+			"target":    item.record.Id,
+			"rule":      item.rule,
+			"stage":     stage,
+			"recipient": recipient.Id,
+			"since":     types.NowDateTime().Add(-repeat),
+		},
+	)
+	if previous != nil {
+		return
+	}
+
+	days := int(time.Since(item.since).Hours() / 24)
+	text := fmt.Sprintf("⏰ Waiting for %d days\n\n%s\n\n%s", days, item.summary, appURL())
+	subject := "Reminder: waiting for your action"
+	if stage == reminderStageEscalate {
+		text = fmt.Sprintf("🚨 Overdue for %d days, the leader has been reminded\n\n%s\n\n%s", days, item.summary, appURL())
+		subject = "Escalation: overdue membership action"
+	}
+
+	channel, err := deliverReminder(recipient, subject, text)
+	if err != nil {
+		log.Printf("Failed to send %s reminder to %s: %v", stage, recipient.GetString("email"), err)
+		return
+	}
+
+	collection, err := app.FindCollectionByNameOrId("reminders")
+	if err != nil {
+		log.Printf("Failed to find reminders collection: %v", err)
+		return
+	}
+
+	reminder := core.NewRecord(collection)
+	reminder.Set("target_collection", item.collection)
+	reminder.Set("target", item.record.Id)
+	reminder.Set("rule", item.rule)
+	reminder.Set("stage", stage)
+	reminder.Set("recipient", recipient.Id)
+	reminder.Set("channel", channel)
+	reminder.Set("sent_at", types.NowDateTime())
+	if err := app.Save(reminder); err != nil {
+		log.Printf("Failed to save reminder: %v", err)
+		return
+	}
+
+	log.Printf("✓ Sent %s reminder (%s) to %s via %s", stage, item.rule, recipient.GetString("email"), channel)
+}
+
+// deliverReminder sends the text by Telegram when possible, by email otherwise.
+func deliverReminder(recipient *core.Record, subject string, text string) (string, error) {
+	if telegramID, bot := userTelegramID(recipient), currentBot(); bot != nil && telegramID != 0 {
+		msg := tgbotapi.NewMessage(telegramID, text)
+		msg.DisableWebPagePreview = true
+		if _, err := bot.Send(msg); err == nil {
+			return "telegram", nil
+		}
+	}
+
+	message := &mailer.Message{
+		From: mail.Address{
+			Address: app.Settings().Meta.SenderAddress,
+			Name:    app.Settings().Meta.SenderName,
+		},
+		To:      []mail.Address{{Address: recipient.GetString("email")}},
+		Subject: subject,
+		Text:    text,
+	}
+
+	if err := app.NewMailClient().Send(message); err != nil {
+		return "", err
+	}
+
+	return "email", nil
+}
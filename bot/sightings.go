@@ -0,0 +1,234 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// UnknownMember is a Telegram user seen in a group without a linked account.
+type UnknownMember struct {
+	TelegramID int64  `json:"telegram_id"`
+	Username   string `json:"username"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Status     string `json:"status"`
+	SeenAt     string `json:"seen_at"`
+}
+
+// UnknownMembersReport lists the unregistered people currently in a group.
+type UnknownMembersReport struct {
+	GroupID   string          `json:"group_id"`
+	GroupName string          `json:"group_name"`
+	Members   []UnknownMember `json:"members"`
+}
+
+// isInChatStatus reports whether a chat member status means the user is in the chat.
+func isInChatStatus(status string) bool {
+	return status == "member" || status == "restricted" || status == "administrator" || status == "creator"
+}
+
+// recordTelegramSighting stores or updates the last known status of an
+// unregistered Telegram user in a group.
+func recordTelegramSighting(update *tgbotapi.ChatMemberUpdated) *core.Record {
+	group, err := findGroupByChatID(update.Chat.ID)
+	if err != nil {
+		return nil
+	}
+
+	from := update.NewChatMember.User
+
+	sighting, _ := app.FindFirstRecordByFilter(
+		"telegram_sightings",
+		"telegram_id = {:id} && group = {:group}",
+		map[string]any{
+			"id":    from.ID,
+			"group": group.Id,
+		},
+	)
+	if sighting == nil {
+		collection, err := app.FindCollectionByNameOrId("telegram_sightings")
+		if err != nil {
+			log.Printf("Failed to find telegram_sightings collection: %v", err)
+			return nil
+		}
+		sighting = core.NewRecord(collection)
+		sighting.Set("telegram_id", from.ID)
+		sighting.Set("group", group.Id)
+	}
+
+	sighting.Set("username", from.UserName)
+	sighting.Set("first_name", from.FirstName)
+	sighting.Set("last_name", from.LastName)
+	sighting.Set("status", update.NewChatMember.Status)
+	sighting.Set("seen_at", types.NowDateTime())
+	if !isInChatStatus(update.NewChatMember.Status) {
+		sighting.Set("restricted_at", nil)
+	}
+
+	if err := app.Save(sighting); err != nil {
+		log.Printf("Failed to save Telegram sighting: %v", err)
+		return nil
+	}
+
+	log.Printf("✓ Recorded unregistered Telegram ID %d as '%s' in '%s'", from.ID, update.NewChatMember.Status, group.GetString("name"))
+
+	return sighting
+}
+
+// applyTelegramSightings creates user_groups records from the sightings of a
+// freshly linked Telegram identity and removes the sightings.
+func applyTelegramSightings(user *core.Record, telegramID int64) {
+	sightings, err := app.FindRecordsByFilter(
+		"telegram_sightings",
+		"telegram_id = {:id}",
+		"",
+		0,
+		0,
+		map[string]any{"id": telegramID},
+	)
+	if err != nil || len(sightings) == 0 {
+		return
+	}
+
+	userGroupsCollection, err := app.FindCollectionByNameOrId("user_groups")
+	if err != nil {
+		log.Printf("Failed to find user_groups collection: %v", err)
+		return
+	}
+
+	restrictedGroups := []string{}
+	err = app.RunInTransaction(func(txApp core.App) error {
+		for _, sighting := range sightings {
+			if !sighting.GetDateTime("restricted_at").IsZero() {
+				restrictedGroups = append(restrictedGroups, sighting.GetString("group"))
+			}
+
+			status := sighting.GetString("status")
+			if isInChatStatus(status) {
+				existingRecord, _ := txApp.FindFirstRecordByFilter(
+					"user_groups",
+					"user = {:user} && group = {:group}",
+					map[string]any{
+						"user":  user.Id,
+						"group": sighting.GetString("group"),
+					},
+				)
+				if existingRecord == nil {
+					role := "member"
+					if status == "administrator" || status == "creator" {
+						role = "admin"
+					}
+
+					userGroupRecord := core.NewRecord(userGroupsCollection)
+					userGroupRecord.Set("user", user.Id)
+					userGroupRecord.Set("group", sighting.GetString("group"))
+					userGroupRecord.Set("role", role)
+					if err := txApp.Save(userGroupRecord); err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := txApp.Delete(sighting); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to apply Telegram sightings for %s: %v", user.GetString("email"), err)
+		return
+	}
+
+	log.Printf("✓ Applied %d Telegram sightings for %s", len(sightings), user.GetString("email"))
+
+	if currentBot() != nil && isActiveMember(user) {
+		// The chat comes from the group, which follows supergroup migrations
+		for _, groupID := range restrictedGroups {
+			group, err := app.FindRecordById("groups", groupID)
+			if err != nil {
+				continue
+			}
+			liftRestriction(groupChatID(group), telegramID)
+		}
+	}
+}
+
+// UnknownMembers returns the unregistered people currently in each active group.
+func UnknownMembers() ([]UnknownMembersReport, error) {
+	if app == nil {
+		return nil, fmt.Errorf("telegram bot not started")
+	}
+
+	groups, err := app.FindRecordsByFilter("groups", "type = 'telegram' && disabled_at = ''", "name", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []UnknownMembersReport{}
+	for _, group := range groups {
+		sightings, err := app.FindRecordsByFilter(
+			"telegram_sightings",
+			"group = {:group} && (status = 'member' || status = 'restricted' || status = 'administrator' || status = 'creator')",
+			"-seen_at",
+			0,
+			0,
+			map[string]any{"group": group.Id},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(sightings) == 0 {
+			continue
+		}
+
+		report := UnknownMembersReport{
+			GroupID:   group.Id,
+			GroupName: group.GetString("name"),
+			Members:   make([]UnknownMember, 0, len(sightings)),
+		}
+		for _, sighting := range sightings {
+			report.Members = append(report.Members, UnknownMember{
+				TelegramID: int64(sighting.GetInt("telegram_id")),
+				Username:   sighting.GetString("username"),
+				FirstName:  sighting.GetString("first_name"),
+				LastName:   sighting.GetString("last_name"),
+				Status:     sighting.GetString("status"),
+				SeenAt:     sighting.GetString("seen_at"),
+			})
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func unknownReply() string {
+	reports, err := UnknownMembers()
+	if err != nil {
+		return "❌ Failed to load unregistered members."
+	}
+	if len(reports) == 0 {
+		return "✅ No unregistered people in the groups."
+	}
+
+	var b strings.Builder
+	b.WriteString("👤 Unregistered people in groups\n")
+	for _, report := range reports {
+		fmt.Fprintf(&b, "\n%s (%d)\n", report.GroupName, len(report.Members))
+		for _, member := range report.Members {
+			name := strings.TrimSpace(member.FirstName + " " + member.LastName)
+			if member.Username != "" {
+				name += " @" + member.Username
+			}
+			fmt.Fprintf(&b, "• %s (ID: %d)\n", strings.TrimSpace(name), member.TelegramID)
+		}
+	}
+
+	return b.String()
+}
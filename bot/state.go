@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// handledUpdatesRetention is how long handled update IDs are kept for deduplication.
+const handledUpdatesRetention = 24 * time.Hour
+
+// BindBotStateJob registers the cron job that prunes old handled update IDs.
+func BindBotStateJob(pbApp *pocketbase.PocketBase) {
+	pbApp.Cron().MustAdd("bot_updates_cleanup", "30 * * * *", func() {
+		deleted, err := cleanupHandledUpdates()
+		if err != nil {
+			log.Printf("Failed to clean up handled updates: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("✓ Removed %d handled update IDs", deleted)
+		}
+	})
+}
+
+// loadBotState reads the data of a bot_state record into dest.
+// A missing record leaves dest untouched.
+func loadBotState(name string, dest any) error {
+	record, err := app.FindFirstRecordByFilter(
+		"bot_state",
+		"name = {:name}",
+		map[string]any{"name": name},
+	)
+	if err != nil {
+		return nil
+	}
+
+	return record.UnmarshalJSONField("data", dest)
+}
+
+// saveBotState creates or updates a bot_state record.
+func saveBotState(name string, data any) error {
+	record, _ := app.FindFirstRecordByFilter(
+		"bot_state",
+		"name = {:name}",
+		map[string]any{"name": name},
+	)
+	if record == nil {
+		collection, err := app.FindCollectionByNameOrId("bot_state")
+		if err != nil {
+			return err
+		}
+		record = core.NewRecord(collection)
+		record.Set("name", name)
+	}
+
+	record.Set("data", data)
+	return app.Save(record)
+}
+
+type updatesState struct {
+	Offset int `json:"offset"`
+}
+
+// updatesStateName is the bot_state record of the update offset. Update IDs
+// are per bot, so a new bot token starts with its own offset.
+func updatesStateName(botID int64) string {
+	return fmt.Sprintf("updates:%d", botID)
+}
+
+// loadUpdateOffset returns the update ID to resume getUpdates from.
+func loadUpdateOffset(botID int64) int {
+	var state updatesState
+	if err := loadBotState(updatesStateName(botID), &state); err != nil {
+		log.Printf("Failed to load update offset: %v", err)
+	}
+	return state.Offset
+}
+
+// updateTracker keeps the offset below every update that is still queued or
+// being handled, so a restart never skips an update.
+type updateTracker struct {
+	mu       sync.Mutex
+	botID    int64
+	inFlight map[int]bool
+	next     int
+	saved    int
+}
+
+func newUpdateTracker(botID int64, offset int) *updateTracker {
+	return &updateTracker{
+		botID:    botID,
+		inFlight: map[int]bool{},
+		next:     offset,
+		saved:    offset,
+	}
+}
+
+func (t *updateTracker) start(updateID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight[updateID] = true
+	if updateID >= t.next {
+		t.next = updateID + 1
+	}
+}
+
+// done marks the update as handled and persists the new offset when it moved.
+func (t *updateTracker) done(updateID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.inFlight, updateID)
+
+	offset := t.next
+	for id := range t.inFlight {
+		if id < offset {
+			offset = id
+		}
+	}
+
+	if offset <= t.saved {
+		return
+	}
+
+	if err := saveBotState(updatesStateName(t.botID), updatesState{Offset: offset}); err != nil {
+		log.Printf("Failed to save update offset: %v", err)
+		return
+	}
+	t.saved = offset
+}
+
+// wasUpdateHandled reports whether the update of the bot was already handled,
+// e.g. when Telegram redelivers it after a restart.
+func wasUpdateHandled(botID int64, updateID int) bool {
+	record, _ := app.FindFirstRecordByFilter(
+		"bot_updates",
+		"bot_id = {:bot} && update_id = {:id}",
+		map[string]any{
+			"bot": botID,
+			"id":  updateID,
+		},
+	)
+	return record != nil
+}
+
+func markUpdateHandled(botID int64, updateID int) {
+	collection, err := app.FindCollectionByNameOrId("bot_updates")
+	if err != nil {
+		log.Printf("Failed to find bot_updates collection: %v", err)
+		return
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("bot_id", botID)
+	record.Set("update_id", updateID)
+	if err := app.Save(record); err != nil {
+		log.Printf("Failed to record handled update %d: %v", updateID, err)
+	}
+}
+
+// handleUpdateOnce handles an update unless it was handled before.
+func handleUpdateOnce(botID int64, update tgbotapi.Update) {
+	if wasUpdateHandled(botID, update.UpdateID) {
+		log.Printf("Skipping already handled update %d", update.UpdateID)
+		return
+	}
+
+	updatesTotal.Inc(updateType(update))
+	handleUpdate(update)
+	markUpdateHandled(botID, update.UpdateID)
+}
+
+func cleanupHandledUpdates() (int, error) {
+	records, err := app.FindRecordsByFilter(
+		"bot_updates",
+		"created < {:before}",
+		"",
+		0,
+		0,
+		map[string]any{
+			"before": types.NowDateTime().Add(-handledUpdatesRetention),
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, record := range records {
+		if err := app.Delete(record); err != nil {
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
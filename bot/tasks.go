@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// shutdownTimeout bounds how long StopTelegramBot waits for running tasks.
+const shutdownTimeout = 10 * time.Second
+
+// tasks tracks the background work of the bot so shutdown can wait for it.
+var tasks = struct {
+	sync.Mutex
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	nextID  int
+	running map[int]string
+}{
+	running: map[int]string{},
+}
+
+// startTasks creates the context that is cancelled when the bot stops.
+func startTasks() {
+	tasks.Lock()
+	defer tasks.Unlock()
+
+	tasks.ctx, tasks.cancel = context.WithCancel(context.Background())
+	tasks.wg = &sync.WaitGroup{}
+	tasks.running = map[int]string{}
+}
+
+// trackTask registers a task and returns its context and a done function.
+// ok is false once the bot is stopping; the task must not run then.
+func trackTask(name string) (ctx context.Context, done func(), ok bool) {
+	tasks.Lock()
+	defer tasks.Unlock()
+
+	if tasks.ctx == nil || tasks.ctx.Err() != nil {
+		log.Printf("Bot is stopping, task '%s' not started", name)
+		return nil, nil, false
+	}
+
+	tasks.nextID++
+	id := tasks.nextID
+	tasks.running[id] = name
+	wg := tasks.wg
+	wg.Add(1)
+
+	return tasks.ctx, func() {
+		tasks.Lock()
+		delete(tasks.running, id)
+		tasks.Unlock()
+		wg.Done()
+	}, true
+}
+
+// goTask runs fn in a tracked goroutine.
+func goTask(name string, fn func(ctx context.Context)) {
+	ctx, done, ok := trackTask(name)
+	if !ok {
+		return
+	}
+
+	go func() {
+		defer done()
+		fn(ctx)
+	}()
+}
+
+// runTask runs fn as a tracked task in the calling goroutine (e.g. cron jobs).
+func runTask(name string, fn func(ctx context.Context)) {
+	ctx, done, ok := trackTask(name)
+	if !ok {
+		return
+	}
+
+	defer done()
+	fn(ctx)
+}
+
+// sleepContext waits for d and returns false when ctx is cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// stopTasks cancels the running tasks and waits up to shutdownTimeout for them.
+// Tasks still running after that are logged as not finished. The returned
+// channel is closed once all of them have returned.
+func stopTasks() <-chan struct{} {
+	finished := make(chan struct{})
+
+	tasks.Lock()
+	if tasks.cancel == nil {
+		tasks.Unlock()
+		close(finished)
+		return finished
+	}
+	tasks.cancel()
+	wg := tasks.wg
+	tasks.Unlock()
+
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		log.Printf("✓ All bot tasks finished")
+	case <-time.After(shutdownTimeout):
+		tasks.Lock()
+		names := make([]string, 0, len(tasks.running))
+		for _, name := range tasks.running {
+			names = append(names, name)
+		}
+		tasks.Unlock()
+
+		sort.Strings(names)
+		for _, name := range names {
+			log.Printf("Bot task '%s' not finished after %s", name, shutdownTimeout)
+		}
+	}
+
+	pendingSyncs.Lock()
+	for groupID := range pendingSyncs.groups {
+		log.Printf("Membership sync of group %s not finished", groupID)
+	}
+	for userID := range pendingSyncs.users {
+		log.Printf("Membership sync of user %s not finished", userID)
+	}
+	pendingSyncs.Unlock()
+
+	return finished
+}
@@ -1,28 +1,48 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"sync/atomic"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/tokens"
 )
 
-var bot *tgbotapi.BotAPI
+// client is the Bot API client of the active instance, nil on standby.
+// Read it once per function with currentBot and use that snapshot.
+var client atomic.Pointer[tgbotapi.BotAPI]
 var app *pocketbase.PocketBase
 
+// errUserNotFound is returned when the user of a connect token no longer exists.
+var errUserNotFound = errors.New("user not found")
+
 // GetBot returns the bot instance
 func GetBot() *tgbotapi.BotAPI {
-	return bot
+	return currentBot()
+}
+
+// currentBot returns the client, or nil while this instance is not active.
+func currentBot() *tgbotapi.BotAPI {
+	return client.Load()
 }
 
 // StartTelegramBot initializes and starts the Telegram bot
-func StartTelegramBot(pbApp *pocketbase.PocketBase) error {
+func StartTelegramBot(pbApp *pocketbase.PocketBase) (err error) {
 	app = pbApp
 
+	username := ""
+	defer func() {
+		recordStart(username, err)
+	}()
+
 	// Get bot token from settings
 	telegramRecord, err := app.FindFirstRecordByFilter(
 		"settings",
@@ -46,179 +66,198 @@ func StartTelegramBot(pbApp *pocketbase.PocketBase) error {
 		return fmt.Errorf("telegram bot token not configured")
 	}
 
-	bot, err = tgbotapi.NewBotAPI(telegramData.Token)
+	api, err := newBotAPI(telegramData.Token)
 	if err != nil {
 		return fmt.Errorf("failed to create bot: %w", err)
 	}
 
-	log.Printf("Telegram bot authorized: @%s", bot.Self.UserName)
+	log.Printf("Telegram bot authorized: @%s", api.Self.UserName)
+	username = api.Self.UserName
 
-	// Start listening for updates
-	go listenForUpdates()
-	// Catch-up for group names when the app was offline.
-	go syncGroupNames()
+	// Only the instance holding the lock receives updates, the others stand by
+	lease.Lock()
+	defer lease.Unlock()
+
+	if lease.stop != nil {
+		return fmt.Errorf("telegram bot already started")
+	}
+
+	lease.stop = make(chan struct{})
+	lease.done = make(chan struct{})
+	go runLease(api, lease.stop, lease.done)
 
 	return nil
 }
 
-// StopTelegramBot stops the update receiver if it is running.
+// StopTelegramBot stops the update receiver if it is running, waits for the
+// in-flight work to finish and releases the bot lock.
 func StopTelegramBot() {
-	if bot == nil {
+	lease.Lock()
+	defer lease.Unlock()
+
+	if lease.stop == nil {
 		return
 	}
 
-	bot.StopReceivingUpdates()
+	close(lease.stop)
+	<-lease.done
+	lease.stop = nil
+	lease.done = nil
+
 	log.Printf("Telegram bot stopped")
 }
 
-func listenForUpdates() {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	u.AllowedUpdates = []string{"message", "my_chat_member", "chat_member"}
+func listenForUpdates(ctx context.Context) {
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
 
-	updates := bot.GetUpdatesChan(u)
+	// Resume after the last update this bot handled before a restart
+	offset := loadUpdateOffset(bot.Self.ID)
 
-	for update := range updates {
-		// Handle bot added/removed from groups
-		if update.MyChatMember != nil {
-			handleChatMemberUpdate(update.MyChatMember)
-			continue
-		}
+	u := tgbotapi.NewUpdate(offset)
+	u.Timeout = 60
+	u.AllowedUpdates = []string{"message", "channel_post", "my_chat_member", "chat_member", "chat_join_request", "callback_query"}
 
-		// Handle user added/removed from groups
-		if update.ChatMember != nil {
-			handleUserChatMemberUpdate(update.ChatMember)
-			continue
-		}
+	updates := pollUpdates(ctx, bot, u)
 
-		if update.Message == nil {
-			continue
-		}
+	dispatchUpdates(ctx, updates, newUpdateTracker(bot.Self.ID, offset))
+}
 
-		// Handle group name change
-		if update.Message.NewChatTitle != "" {
-			updateGroupName(update.Message.Chat.ID, update.Message.NewChatTitle)
-			continue
-		}
+// handleUpdate routes a single update to its handler.
+func handleUpdate(update tgbotapi.Update) {
+	// Handle bot added/removed from groups
+	if update.MyChatMember != nil {
+		handleChatMemberUpdate(update.MyChatMember)
+		return
+	}
 
-		// Handle /start command with token
-		if update.Message.IsCommand() && update.Message.Command() == "start" {
-			args := update.Message.CommandArguments()
-			if args == "" {
-				sendWarningMessage(update.Message.Chat.ID)
-			} else {
-				handleStartCommand(update.Message, args)
-			}
-			continue
-		}
+	// Handle user added/removed from groups
+	if update.ChatMember != nil {
+		handleUserChatMemberUpdate(update.ChatMember)
+		return
+	}
 
-		// Handle private messages (non-commands)
-		if update.Message.Chat.IsPrivate() && !update.Message.IsCommand() {
-			handlePrivateMessage(update.Message)
-		}
+	// Handle join requests (invite links with approval)
+	if update.ChatJoinRequest != nil {
+		handleChatJoinRequest(update.ChatJoinRequest)
+		return
 	}
-}
 
-func syncGroupNames() {
-	groups, err := app.FindRecordsByFilter("groups", "type = 'telegram'", "", 0, 0)
-	if err != nil {
+	// Handle channel service messages
+	if update.ChannelPost != nil {
+		handleChannelPost(update.ChannelPost)
 		return
 	}
 
-	for _, group := range groups {
-		var telegramGroupData struct {
-			ChatID string `json:"chat_id"`
-		}
-		if err := group.UnmarshalJSONField("telegram", &telegramGroupData); err != nil {
-			continue
-		}
-		if telegramGroupData.ChatID == "" {
-			continue
-		}
+	// Handle inline keyboard buttons
+	if update.CallbackQuery != nil {
+		handleCallbackQuery(update.CallbackQuery)
+		return
+	}
 
-		var chatID int64
-		fmt.Sscanf(telegramGroupData.ChatID, "%d", &chatID)
-		if chatID == 0 {
-			continue
-		}
+	if update.Message == nil {
+		return
+	}
 
-		chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{
-			ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
-		})
-		if err != nil || chat.Title == "" {
-			continue
-		}
+	// Handle group upgraded to supergroup (chat ID changes)
+	if update.Message.MigrateToChatID != 0 {
+		migrateGroupChat(update.Message.Chat.ID, update.Message.MigrateToChatID)
+		return
+	}
+	if update.Message.MigrateFromChatID != 0 {
+		migrateGroupChat(update.Message.MigrateFromChatID, update.Message.Chat.ID)
+		return
+	}
 
-		if group.GetString("name") == chat.Title {
-			continue
-		}
+	// Handle group name change
+	if update.Message.NewChatTitle != "" {
+		updateGroupName(update.Message.Chat.ID, update.Message.NewChatTitle)
+		return
+	}
 
-		group.Set("name", chat.Title)
-		if err := app.Save(group); err != nil {
-			log.Printf("Failed to sync group name: %v", err)
+	// Handle group photo change
+	if len(update.Message.NewChatPhoto) > 0 || update.Message.DeleteChatPhoto {
+		syncGroupMetadataByChatID(update.Message.Chat.ID)
+		return
+	}
+
+	// Handle /start command with token
+	if update.Message.IsCommand() && update.Message.Command() == "start" {
+		args := update.Message.CommandArguments()
+		if args == "" {
+			sendWarningMessage(update.Message.Chat.ID)
 		} else {
-			log.Printf("✓ Synced group name to '%s' (ID: %d)", chat.Title, chatID)
+			handleStartCommand(update.Message, args)
 		}
+		return
+	}
+
+	// Handle member commands in private chat
+	if update.Message.Chat.IsPrivate() && update.Message.IsCommand() {
+		handlePrivateCommand(update.Message)
+		return
+	}
+
+	// Handle private messages (non-commands)
+	if update.Message.Chat.IsPrivate() && !update.Message.IsCommand() {
+		handlePrivateMessage(update.Message)
 	}
 }
 
 func handleStartCommand(message *tgbotapi.Message, token string) {
-	service := "telegram_connect"
-	now := types.NowDateTime()
-
-	// Find token in database
-	tokenRecord, err := app.FindFirstRecordByFilter(
-		"tokens",
-		"token = {:token} && service = {:service} && used_at = '' && expires_at > {:now}",
-		map[string]any{
-			"token":   token,
-			"service": service,
-			"now":     now,
-		},
-	)
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
 
-	if err != nil {
+	// The token is consumed in the transaction that saves the link, so it is
+	// used once and stays valid when the link fails
+	var user *core.Record
+	var owner *core.Record
+	var previousID int64
+	err := app.RunInTransaction(func(txApp core.App) error {
+		tokenRecord, err := tokens.Consume(txApp, token, tokens.ServiceTelegramConnect)
+		if err != nil {
+			return err
+		}
+
+		user, err = txApp.FindRecordById("users", tokenRecord.GetString("user"))
+		if err != nil {
+			return errUserNotFound
+		}
+
+		previousID, owner, err = saveTelegramIdentity(txApp, user, message.From)
+		return err
+	})
+	if errors.Is(err, tokens.ErrInvalidToken) {
 		log.Printf("Invalid token: %s", token)
 		reply := tgbotapi.NewMessage(message.Chat.ID, "❌ Invalid or expired token. Please try again from the dashboard.")
 		bot.Send(reply)
 		return
 	}
-
-	// Get user from token
-	userId := tokenRecord.GetString("user")
-	user, err := app.FindRecordById("users", userId)
-	if err != nil {
-		log.Printf("User not found: %s", userId)
+	if errors.Is(err, errUserNotFound) {
+		log.Printf("User of token %s not found", token)
 		reply := tgbotapi.NewMessage(message.Chat.ID, "❌ User not found. Please try again.")
 		bot.Send(reply)
 		return
 	}
-
-	// Prepare Telegram data
-	telegramData := map[string]interface{}{
-		"id":         message.From.ID,
-		"username":   message.From.UserName,
-		"first_name": message.From.FirstName,
-		"last_name":  message.From.LastName,
+	if errors.Is(err, ErrTelegramAlreadyLinked) {
+		openTelegramConflict(owner, user, message.From)
+		log.Printf("Telegram ID %d already linked, conflict opened for %s", message.From.ID, user.GetString("email"))
+		reply := tgbotapi.NewMessage(message.Chat.ID, "⚠️ This Telegram account is already connected to another member account.\n\nAn admin has been notified and will review it. You will get a message here once it is resolved.")
+		bot.Send(reply)
+		return
 	}
-
-	// Update user's telegram field (PocketBase handles JSON serialization)
-	user.Set("telegram", telegramData)
-	if err := app.Save(user); err != nil {
+	if err != nil {
 		log.Printf("Failed to update user: %v", err)
 		reply := tgbotapi.NewMessage(message.Chat.ID, "❌ Failed to save connection.")
 		bot.Send(reply)
 		return
 	}
 
-	// Mark token as used (one-shot)
-	tokenRecord.Set("used_at", now)
-	if err := app.Save(tokenRecord); err != nil {
-		log.Printf("Failed to mark token used: %v", err)
-	}
-
-	// Sync user group memberships
-	go syncUserGroupMemberships(user)
+	completeTelegramLink(user, message.From, previousID)
 
 	// Build success message
 	email := user.GetString("email")
@@ -262,8 +301,8 @@ func handleStartCommand(message *tgbotapi.Message, token string) {
 }
 
 func handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
-	// Only handle groups/supergroups, not private chats
-	if update.Chat.Type != "group" && update.Chat.Type != "supergroup" {
+	// Only handle groups/supergroups/channels, not private chats
+	if !isManagedChat(update.Chat.Type) {
 		return
 	}
 
@@ -275,7 +314,7 @@ func handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 
 	// Bot became admin
 	if newStatus == "administrator" {
-		// Find existing group or create new
+		// Find existing group (active or disabled) or create new
 		chatIDStr := fmt.Sprintf("%d", chatID)
 		group, err := app.FindFirstRecordByFilter(
 			"groups",
@@ -283,7 +322,8 @@ func handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 			map[string]any{"id": chatIDStr},
 		)
 
-		if err != nil {
+		isNew := err != nil
+		if isNew {
 			// Create new group
 			collection, err := app.FindCollectionByNameOrId("groups")
 			if err != nil {
@@ -293,13 +333,23 @@ func handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 			group = core.NewRecord(collection)
 		}
 
+		restored := !isNew && !group.GetDateTime("disabled_at").IsZero()
+
 		// Update group data
+		telegramData := map[string]any{}
+		group.UnmarshalJSONField("telegram", &telegramData)
+		telegramData["chat_id"] = chatIDStr
+		telegramData["type"] = update.Chat.Type
+
 		group.Set("name", update.Chat.Title)
 		group.Set("type", "telegram")
-		group.Set("telegram", map[string]any{
-			"chat_id": chatIDStr,
-			"type":    update.Chat.Type,
-		})
+		group.Set("kind", chatKind(update.Chat.Type))
+		group.Set("telegram", telegramData)
+		group.Set("disabled_at", nil)
+		if isNew {
+			group.Set("gate_mode", "off")
+			group.Set("gate_timeout", 1440)
+		}
 
 		if err := app.Save(group); err != nil {
 			log.Printf("Failed to save group: %v", err)
@@ -308,14 +358,24 @@ func handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 
 		log.Printf("Group '%s' saved successfully", update.Chat.Title)
 
-		// Send welcome message
-		go sendWelcomeMessage(chatID)
+		if restored {
+			notifyAdmins(fmt.Sprintf("✅ The bot is admin again in '%s'. The group is active again.", update.Chat.Title))
+		}
+
+		// Fill description, photo, invite link and member count
+		syncGroupMetadata(group)
 
-		// Sync all connected users with new group
-		go syncAllUsersWithNewGroup()
+		// Send welcome message (never posted into channels)
+		if isNew && !update.Chat.IsChannel() {
+			sendWelcomeMessage(chatID)
+		}
+
+		// Check the connected users against this group only
+		queueGroupSync(group.Id)
 	}
 
-	// Bot lost admin or was removed (member -> not admin, or kicked/left)
+	// Bot lost admin or was removed (member -> not admin, or kicked/left).
+	// The group is disabled, not deleted, so leader, regions and memberships survive.
 	if newStatus == "member" || newStatus == "left" || newStatus == "kicked" {
 		chatIDStr := fmt.Sprintf("%d", chatID)
 		group, err := app.FindFirstRecordByFilter(
@@ -324,35 +384,26 @@ func handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 			map[string]any{"id": chatIDStr},
 		)
 
-		if err == nil && group != nil {
-			// Delete all user_groups records first
-			userGroupRecords, err := app.FindRecordsByFilter(
-				"user_groups",
-				"group = {:group}",
-				"",
-				0,
-				0,
-				map[string]any{"group": group.Id},
-			)
-			if err == nil {
-				for _, ug := range userGroupRecords {
-					app.Delete(ug)
-				}
-			}
-
-			// Now delete the group
-			if err := app.Delete(group); err != nil {
-				log.Printf("Failed to delete group: %v", err)
+		if err == nil && group != nil && group.GetDateTime("disabled_at").IsZero() {
+			group.Set("disabled_at", types.NowDateTime())
+			if err := app.Save(group); err != nil {
+				log.Printf("Failed to disable group: %v", err)
 				return
 			}
-			log.Printf("Group '%s' removed from database", update.Chat.Title)
+			log.Printf("Group '%s' disabled (bot status: %s)", update.Chat.Title, newStatus)
+
+			notifyAdmins(fmt.Sprintf(
+				"⚠️ The bot is no longer admin in '%s' (status: %s).\n\nThe group is hidden and no new members are assigned to it. Make the bot admin again to restore it.",
+				update.Chat.Title,
+				newStatus,
+			))
 		}
 	}
 }
 
 func handleUserChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
-	// Only handle groups/supergroups
-	if update.Chat.Type != "group" && update.Chat.Type != "supergroup" {
+	// Only handle groups/supergroups/channels
+	if !isManagedChat(update.Chat.Type) {
 		return
 	}
 
@@ -369,6 +420,10 @@ func handleUserChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 
 	if err != nil {
 		log.Printf("User with Telegram ID %d not found in DB", userTelegramID)
+		sighting := recordTelegramSighting(update)
+		if !enforceMembersOnly(update, nil) {
+			restrictUnknownJoiner(update, sighting)
+		}
 		return
 	}
 
@@ -385,6 +440,10 @@ func handleUserChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 		return
 	}
 
+	if enforceMembersOnly(update, user) {
+		return
+	}
+
 	// User joined or became admin/creator
 	if newStatus == "member" || newStatus == "administrator" || newStatus == "creator" {
 		role := "member"
@@ -454,7 +513,13 @@ func handleUserChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 	}
 }
 
-func syncAllUsersWithNewGroup() {
+// syncUsersWithGroup checks every linked user against one group.
+func syncUsersWithGroup(ctx context.Context, group *core.Record) {
+	chatID := groupChatID(group)
+	if chatID == 0 {
+		return
+	}
+
 	users, err := app.FindRecordsByFilter(
 		"users",
 		"telegram.id != null && telegram.id != ''",
@@ -467,81 +532,76 @@ func syncAllUsersWithNewGroup() {
 	}
 
 	for _, user := range users {
-		syncUserGroupMemberships(user)
+		syncUserGroupMembership(user, group, chatID)
+		if !sleepContext(ctx, syncInterval) {
+			return
+		}
 	}
 }
 
-func syncUserGroupMemberships(user *core.Record) {
-	// Get user's telegram data
-	var telegramData struct {
-		ID int64 `json:"id"`
-	}
-
-	if err := user.UnmarshalJSONField("telegram", &telegramData); err != nil {
-		return
-	}
-
-	if telegramData.ID == 0 {
-		return
-	}
-
-	// Get all telegram groups
-	groups, err := app.FindRecordsByFilter("groups", "type = 'telegram'", "-created", 0, 0)
+// syncUserGroupMemberships checks one user against every active group.
+func syncUserGroupMemberships(ctx context.Context, user *core.Record) {
+	// Get all active telegram groups
+	groups, err := app.FindRecordsByFilter("groups", "type = 'telegram' && disabled_at = ''", "-created", 0, 0)
 	if err != nil {
 		return
 	}
 
 	for _, group := range groups {
-		var telegramGroupData struct {
-			ChatID string `json:"chat_id"`
-		}
-
-		if err := group.UnmarshalJSONField("telegram", &telegramGroupData); err != nil {
+		chatID := groupChatID(group)
+		if chatID == 0 {
 			continue
 		}
 
-		if telegramGroupData.ChatID == "" {
-			continue
+		syncUserGroupMembership(user, group, chatID)
+		if !sleepContext(ctx, syncInterval) {
+			return
 		}
+	}
+}
 
-		var chatID int64
-		fmt.Sscanf(telegramGroupData.ChatID, "%d", &chatID)
+// syncUserGroupMembership creates the user_groups record when the user is in the chat.
+func syncUserGroupMembership(user *core.Record, group *core.Record, chatID int64) {
+	telegramID := userTelegramID(user)
+	bot := currentBot()
+	if telegramID == 0 || bot == nil {
+		return
+	}
 
-		chatMember, err := bot.GetChatMember(tgbotapi.GetChatMemberConfig{
-			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-				ChatID: chatID,
-				UserID: telegramData.ID,
-			},
-		})
+	chatMember, err := bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chatID,
+			UserID: telegramID,
+		},
+	})
 
-		if err != nil {
-			continue
+	if err != nil {
+		return
+	}
+
+	if chatMember.Status == "member" || chatMember.Status == "administrator" || chatMember.Status == "creator" {
+		role := "member"
+		if chatMember.Status == "administrator" || chatMember.Status == "creator" {
+			role = "admin"
 		}
 
-		if chatMember.Status == "member" || chatMember.Status == "administrator" || chatMember.Status == "creator" {
-			role := "member"
-			if chatMember.Status == "administrator" || chatMember.Status == "creator" {
-				role = "admin"
-			}
+		existingRecord, _ := app.FindFirstRecordByFilter(
+			"user_groups",
+			"user = {:user} && group = {:group}",
+			map[string]any{
+				"user":  user.Id,
+				"group": group.Id,
+			},
+		)
 
-			existingRecord, _ := app.FindFirstRecordByFilter(
-				"user_groups",
-				"user = {:user} && group = {:group}",
-				map[string]any{
-					"user":  user.Id,
-					"group": group.Id,
-				},
-			)
-
-			if existingRecord == nil {
-				userGroupsCollection, _ := app.FindCollectionByNameOrId("user_groups")
-				if userGroupsCollection != nil {
-					userGroupRecord := core.NewRecord(userGroupsCollection)
-					userGroupRecord.Set("user", user.Id)
-					userGroupRecord.Set("group", group.Id)
-					userGroupRecord.Set("role", role)
-					app.Save(userGroupRecord)
-				}
+		if existingRecord == nil {
+			userGroupsCollection, _ := app.FindCollectionByNameOrId("user_groups")
+			if userGroupsCollection != nil {
+				userGroupRecord := core.NewRecord(userGroupsCollection)
+				userGroupRecord.Set("user", user.Id)
+				userGroupRecord.Set("group", group.Id)
+				userGroupRecord.Set("role", role)
+				app.Save(userGroupRecord)
 			}
 		}
 	}
@@ -589,6 +649,11 @@ func sendWelcomeMessage(chatID int64) {
 	// Replace {url} placeholder
 	message := strings.ReplaceAll(messagesData.Welcome, "{url}", urlData.Address)
 
+	bot := currentBot()
+	if bot == nil {
+		return
+	}
+
 	msg := tgbotapi.NewMessage(chatID, message)
 	if _, err := bot.Send(msg); err != nil {
 		log.Printf("Failed to send welcome message: %v", err)
@@ -641,7 +706,8 @@ func sendWarningMessage(chatID int64) {
 	}
 
 	message := strings.ReplaceAll(messagesData.Warning, "{url}", urlData.Address)
-	if message == "" {
+	bot := currentBot()
+	if message == "" || bot == nil {
 		return
 	}
 
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// updateWorkers bounds how many updates are handled at the same time.
+	updateWorkers = 8
+	// updateQueueSize is the backlog per worker before getUpdates is slowed down.
+	updateQueueSize = 100
+	// syncInterval spaces getChatMember calls of membership syncs.
+	syncInterval = 50 * time.Millisecond
+)
+
+// dispatchUpdates hands updates to a fixed pool of workers. Updates of the
+// same chat always go to the same worker, so they are handled in order.
+// It returns when the updates channel is closed or ctx is cancelled, once the
+// queued updates are handled.
+func dispatchUpdates(ctx context.Context, updates tgbotapi.UpdatesChannel, tracker *updateTracker) {
+	queues := make([]chan tgbotapi.Update, updateWorkers)
+
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan tgbotapi.Update, updateQueueSize)
+		wg.Add(1)
+		go func(queue chan tgbotapi.Update) {
+			defer wg.Done()
+			for update := range queue {
+				handleUpdateOnce(tracker.botID, update)
+				tracker.done(update.UpdateID)
+			}
+		}(queues[i])
+	}
+
+	// getUpdates may hang in a long poll after StopReceivingUpdates,
+	// so stop reading on cancel. Unread updates are fetched again on restart.
+receive:
+	for {
+		select {
+		case <-ctx.Done():
+			break receive
+		case update, ok := <-updates:
+			if !ok {
+				break receive
+			}
+			recordUpdateReceived()
+			tracker.start(update.UpdateID)
+			queues[updateWorker(update)] <- update
+		}
+	}
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+}
+
+// updateWorker picks the worker of the chat the update belongs to.
+func updateWorker(update tgbotapi.Update) int {
+	chatID := updateChatID(update)
+	if chatID < 0 {
+		chatID = -chatID
+	}
+	return int(chatID % updateWorkers)
+}
+
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.MyChatMember != nil:
+		return update.MyChatMember.Chat.ID
+	case update.ChatMember != nil:
+		return update.ChatMember.Chat.ID
+	case update.ChatJoinRequest != nil:
+		return update.ChatJoinRequest.Chat.ID
+	case update.ChannelPost != nil:
+		return update.ChannelPost.Chat.ID
+	case update.CallbackQuery != nil:
+		if update.CallbackQuery.Message != nil {
+			return update.CallbackQuery.Message.Chat.ID
+		}
+		return update.CallbackQuery.From.ID
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	default:
+		return 0
+	}
+}
+
+// pendingSyncs collects membership syncs so repeated requests for the same
+// group or user run once.
+var pendingSyncs = struct {
+	sync.Mutex
+	groups map[string]bool
+	users  map[string]bool
+}{
+	groups: map[string]bool{},
+	users:  map[string]bool{},
+}
+
+var syncWake = make(chan struct{}, 1)
+
+// queueGroupSync schedules a check of every linked user against one group.
+func queueGroupSync(groupID string) {
+	pendingSyncs.Lock()
+	pendingSyncs.groups[groupID] = true
+	pendingSyncs.Unlock()
+	wakeSyncWorker()
+}
+
+// queueUserSync schedules a check of one user against every group.
+func queueUserSync(userID string) {
+	pendingSyncs.Lock()
+	pendingSyncs.users[userID] = true
+	pendingSyncs.Unlock()
+	wakeSyncWorker()
+}
+
+func wakeSyncWorker() {
+	select {
+	case syncWake <- struct{}{}:
+	default:
+	}
+}
+
+// runSyncWorker runs the queued membership syncs one batch at a time.
+// Syncs interrupted by shutdown stay queued so they are reported.
+func runSyncWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncWake:
+		}
+
+		pendingSyncs.Lock()
+		groupIDs := pendingSyncs.groups
+		userIDs := pendingSyncs.users
+		pendingSyncs.groups = map[string]bool{}
+		pendingSyncs.users = map[string]bool{}
+		pendingSyncs.Unlock()
+
+		for groupID := range groupIDs {
+			if ctx.Err() != nil {
+				queueGroupSync(groupID)
+				continue
+			}
+			group, err := app.FindRecordById("groups", groupID)
+			if err != nil {
+				continue
+			}
+			syncUsersWithGroup(ctx, group)
+		}
+
+		for userID := range userIDs {
+			if ctx.Err() != nil {
+				queueUserSync(userID)
+				continue
+			}
+			user, err := app.FindRecordById("users", userID)
+			if err != nil {
+				continue
+			}
+			syncUserGroupMemberships(ctx, user)
+		}
+
+		recordSyncRun()
+
+		if len(groupIDs) > 0 || len(userIDs) > 0 {
+			log.Printf("✓ Synced memberships (groups=%d users=%d)", len(groupIDs), len(userIDs))
+		}
+	}
+}
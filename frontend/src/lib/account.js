@@ -0,0 +1,28 @@
+import { pb } from './pocketbase';
+
+export async function requestAccountDeletion() {
+	const response = await fetch('/api/me/delete/request', {
+		method: 'POST',
+		headers: {
+			Authorization: pb.authStore.token,
+		},
+	});
+
+	if (!response.ok) {
+		throw new Error('Failed to send the confirmation email');
+	}
+}
+
+export async function confirmAccountDeletion(token) {
+	const response = await fetch('/api/me/delete/confirm', {
+		method: 'POST',
+		headers: { 'Content-Type': 'application/json' },
+		body: JSON.stringify({ token }),
+	});
+
+	if (!response.ok) {
+		throw new Error('This link is invalid or has expired');
+	}
+
+	pb.authStore.clear();
+}
@@ -5,6 +5,8 @@ export const queryParams = writable({});
 
 const publicRoutes = ['login', 'signup', 'signup-direct', 'password-reset'];
 const authOnlyRoutes = ['onboarding', 'pending-approval', 'telegram-connect'];
+// Reachable with or without a session, e.g. from a link in an email
+const anyAuthRoutes = ['account-delete'];
 const appPrefix = 'app/';
 export const defaultAppRoute = 'app/profile';
 const appRoutes = [defaultAppRoute, 'app/groups'];
@@ -28,6 +30,8 @@ export function navigate(route) {
 }
 
 export function getTargetRoute(isAuthenticated, user, currentRoute) {
+	if (anyAuthRoutes.includes(currentRoute)) return currentRoute;
+
 	if (!isAuthenticated) {
 		return publicRoutes.includes(currentRoute) ? currentRoute : 'login';
 	}
@@ -1,5 +1,7 @@
 import { pb } from './pocketbase';
 
+const WEBAPP_SESSION_KEY = 'telegram_webapp_session';
+
 export async function generateTelegramDeepLink(botName) {
 	const response = await fetch('/api/telegram/generate-token', {
 		method: 'POST',
@@ -21,3 +23,107 @@ export async function generateTelegramDeepLink(botName) {
 		fallback: `https://t.me/${cleanBotName}?start=${token}`,
 	};
 }
+
+export async function authWithTelegramWebApp(initData) {
+	const response = await fetch('/api/telegram/webapp', {
+		method: 'POST',
+		headers: { 'Content-Type': 'application/json' },
+		body: JSON.stringify({ init_data: initData }),
+	});
+
+	if (!response.ok) {
+		throw new Error('Telegram authentication failed');
+	}
+
+	const data = await response.json();
+	if (data.token && data.record) {
+		pb.authStore.save(data.token, data.record);
+	}
+
+	return data;
+}
+
+export async function linkTelegramWebAppSession(session) {
+	const response = await fetch('/api/telegram/webapp/link', {
+		method: 'POST',
+		headers: {
+			'Content-Type': 'application/json',
+			Authorization: pb.authStore.token,
+		},
+		body: JSON.stringify({ session }),
+	});
+
+	if (!response.ok) {
+		throw new Error('Failed to link Telegram account');
+	}
+
+	return response.json();
+}
+
+export async function unlinkTelegram() {
+	const response = await fetch('/api/telegram/unlink', {
+		method: 'POST',
+		headers: {
+			Authorization: pb.authStore.token,
+		},
+	});
+
+	if (!response.ok) {
+		throw new Error('Failed to disconnect Telegram');
+	}
+
+	return response.json();
+}
+
+// Signs in when the app runs as a Telegram Mini App. Unlinked Telegram users
+// get a limited session that is kept for this tab until they log in.
+export async function initTelegramWebApp() {
+	const webApp = window.Telegram?.WebApp;
+	if (!webApp?.initData) return;
+
+	webApp.ready();
+	if (pb.authStore.isValid) return;
+
+	try {
+		const data = await authWithTelegramWebApp(webApp.initData);
+		if (!data.linked && data.session) {
+			window.sessionStorage.setItem(
+				WEBAPP_SESSION_KEY,
+				JSON.stringify({ session: data.session, telegram: data.telegram })
+			);
+		}
+	} catch (err) {
+		// Fall back to the regular login
+	}
+}
+
+// Offers to link the Telegram account of the Mini App session issued in this
+// tab to the logged in user. Sessions are never taken from the URL.
+export async function redeemTelegramWebAppSession() {
+	const stored = window.sessionStorage.getItem(WEBAPP_SESSION_KEY);
+	if (!stored || !pb.authStore.isValid) return;
+	if (!window.Telegram?.WebApp?.initData) return;
+
+	window.sessionStorage.removeItem(WEBAPP_SESSION_KEY);
+
+	let pending;
+	try {
+		pending = JSON.parse(stored);
+	} catch (err) {
+		return;
+	}
+	if (!pending?.session) return;
+
+	const telegram = pending.telegram || {};
+	const name = telegram.username
+		? `@${telegram.username}`
+		: [telegram.first_name, telegram.last_name].filter(Boolean).join(' ');
+	if (!window.confirm(`Link this Telegram account (${name}) to your account?`)) return;
+
+	try {
+		await linkTelegramWebAppSession(pending.session);
+		await pb.collection('users').authRefresh();
+	} catch (err) {
+		// Session expired or already linked, the connect flow is still available
+	}
+}
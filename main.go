@@ -11,6 +11,8 @@ import (
 	"members/api"
 	"members/bot"
 	_ "members/migrations"
+	"members/retention"
+	"members/tokens"
 )
 
 func init() {
@@ -34,9 +36,24 @@ func main() {
 		}
 
 		// API routes
+		se.Router.GET("/metrics", api.MetricsHandler(app))
+		se.Router.GET("/api/status", api.StatusHandler(app)).Bind(apis.RequireAuth())
 		se.Router.GET("/api/settings/{name}", api.GetSettingsHandler(app))
 		se.Router.POST("/api/signup/check-email", api.CheckSignupEmailHandler(app))
+		se.Router.POST("/api/telegram/auth", api.TelegramAuthHandler(app))
+		se.Router.POST("/api/telegram/webapp", api.TelegramWebAppAuthHandler(app))
+		se.Router.POST("/api/telegram/webapp/link", api.TelegramWebAppLinkHandler(app)).Bind(apis.RequireAuth())
 		se.Router.POST("/api/telegram/generate-token", api.GenerateTelegramTokenHandler(app)).Bind(apis.RequireAuth())
+		se.Router.POST("/api/telegram/unlink", api.UnlinkTelegramHandler(app)).Bind(apis.RequireAuth())
+		se.Router.GET("/api/telegram/conflicts", api.ListTelegramConflictsHandler(app)).Bind(apis.RequireAuth())
+		se.Router.POST("/api/telegram/conflicts/resolve", api.ResolveTelegramConflictHandler(app)).Bind(apis.RequireAuth())
+		se.Router.GET("/api/telegram/unknown-members", api.UnknownMembersHandler(app)).Bind(apis.RequireAuth())
+		se.Router.GET("/api/telegram/lock", api.BotLockStatusHandler(app)).Bind(apis.RequireAuth())
+		se.Router.GET("/api/retention/report", api.RetentionReportHandler(app)).Bind(apis.RequireAuth())
+		se.Router.GET("/api/me/export", api.ExportMyDataHandler(app)).Bind(apis.RequireAuth())
+		se.Router.POST("/api/me/delete/request", api.RequestAccountDeletionHandler(app)).Bind(apis.RequireAuth())
+		se.Router.POST("/api/me/delete/confirm", api.ConfirmAccountDeletionHandler(app))
+		se.Router.GET("/api/broadcasts/{id}/preview", api.BroadcastPreviewHandler(app)).Bind(apis.RequireAuth())
 		se.Router.POST("/api/guardians/leader-approve", api.LeaderApproveGuardianHandler(app)).Bind(apis.RequireAuth())
 		se.Router.POST("/api/guardians/admin-confirm", api.AdminConfirmGuardianHandler(app)).Bind(apis.RequireAuth())
 
@@ -47,6 +64,16 @@ func main() {
 	})
 
 	api.BindRequestHooks(app)
+	api.BindUserHooks(app)
+	api.BindMetrics(app)
+	bot.BindGuardianHooks(app)
+	bot.BindBroadcastJob(app)
+	bot.BindGroupSyncJob(app)
+	bot.BindGateJob(app)
+	bot.BindBotStateJob(app)
+	bot.BindReminderJob(app)
+	tokens.BindCleanupJob(app)
+	retention.BindJob(app)
 
 	if err := app.Start(); err != nil {
 		log.Fatal(err)
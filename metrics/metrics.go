@@ -0,0 +1,273 @@
+// Package metrics is a small registry of counters, gauges and summaries
+// exposed in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Sample is one labelled value of a gauge collected at scrape time.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+var registry = struct {
+	sync.Mutex
+	names   map[string]bool
+	metrics []metric
+}{
+	names: map[string]bool{},
+}
+
+func register(name string, m metric) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if registry.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	registry.names[name] = true
+	registry.metrics = append(registry.metrics, m)
+}
+
+// series stores float values per label value combination.
+type series struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newSeries(name, help, kind string, labels []string) *series {
+	return &series{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		values: map[string]float64{},
+	}
+}
+
+func (s *series) key(labelValues []string) string {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d labels, got %d", s.name, len(s.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (s *series) add(value float64, labelValues []string) {
+	key := s.key(labelValues)
+
+	s.mu.Lock()
+	s.values[key] += value
+	s.mu.Unlock()
+}
+
+func (s *series) set(value float64, labelValues []string) {
+	key := s.key(labelValues)
+
+	s.mu.Lock()
+	s.values[key] = value
+	s.mu.Unlock()
+}
+
+func (s *series) samples() []Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := make([]Sample, 0, len(s.values))
+	for key, value := range s.values {
+		var labelValues []string
+		if len(s.labels) > 0 {
+			labelValues = strings.Split(key, "\xff")
+		}
+		samples = append(samples, Sample{Labels: labelValues, Value: value})
+	}
+	return samples
+}
+
+func (s *series) write(w io.Writer) {
+	writeHeader(w, s.name, s.help, s.kind)
+	writeSamples(w, s.name, s.labels, s.samples())
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	*series
+}
+
+// NewCounter registers a counter with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newSeries(name, help, "counter", labels)}
+	register(name, c)
+	return c
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc(labelValues ...string) {
+	c.add(1, labelValues)
+}
+
+// Add adds a non-negative value to the counter.
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.add(value, labelValues)
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	*series
+}
+
+// NewGauge registers a gauge with the given label names.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newSeries(name, help, "gauge", labels)}
+	register(name, g)
+	return g
+}
+
+// Set sets the gauge.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.set(value, labelValues)
+}
+
+// gaugeFunc is a gauge whose samples are collected on every scrape.
+type gaugeFunc struct {
+	name    string
+	help    string
+	labels  []string
+	collect func() ([]Sample, error)
+}
+
+// NewGaugeFunc registers a gauge computed by collect on every scrape,
+// e.g. from the database.
+func NewGaugeFunc(name, help string, labels []string, collect func() ([]Sample, error)) {
+	register(name, &gaugeFunc{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		collect: collect,
+	})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	samples, err := g.collect()
+	if err != nil {
+		log.Printf("metrics: failed to collect %s: %v", g.name, err)
+		return
+	}
+
+	writeHeader(w, g.name, g.help, "gauge")
+	writeSamples(w, g.name, g.labels, samples)
+}
+
+// Summary tracks the count and sum of observations, e.g. durations.
+type Summary struct {
+	name   string
+	help   string
+	labels []string
+	sum    *series
+	count  *series
+}
+
+// NewSummary registers a summary with the given label names.
+func NewSummary(name, help string, labels ...string) *Summary {
+	s := &Summary{
+		name:   name,
+		help:   help,
+		labels: labels,
+		sum:    newSeries(name+"_sum", "", "", labels),
+		count:  newSeries(name+"_count", "", "", labels),
+	}
+	register(name, s)
+	return s
+}
+
+// Observe records one observation.
+func (s *Summary) Observe(value float64, labelValues ...string) {
+	s.sum.add(value, labelValues)
+	s.count.add(1, labelValues)
+}
+
+func (s *Summary) write(w io.Writer) {
+	writeHeader(w, s.name, s.help, "summary")
+	writeSamples(w, s.name+"_sum", s.labels, s.sum.samples())
+	writeSamples(w, s.name+"_count", s.labels, s.count.samples())
+}
+
+// Write writes every registered metric in the Prometheus text format.
+func Write(w io.Writer) {
+	registry.Lock()
+	all := append([]metric(nil), registry.metrics...)
+	registry.Unlock()
+
+	for _, m := range all {
+		m.write(w)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSamples(w io.Writer, name string, labels []string, samples []Sample) {
+	lines := make([]string, 0, len(samples))
+	for _, sample := range samples {
+		lines = append(lines, name+formatLabels(labels, sample.Labels)+" "+formatValue(sample.Value))
+	}
+	sort.Strings(lines)
+
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+var labelEscaper = strings.NewReplacer("\\", `\\`, "\n", `\n`, `"`, `\"`)
+
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = name + `="` + labelEscaper.Replace(value) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
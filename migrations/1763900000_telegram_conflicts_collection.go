@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		conflicts := core.NewBaseCollection("telegram_conflicts")
+		conflicts.ListRule = nil
+		conflicts.ViewRule = nil
+		conflicts.CreateRule = nil
+		conflicts.UpdateRule = nil
+		conflicts.DeleteRule = nil
+
+		conflicts.Fields.Add(
+			&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.AutodateField{
+				Name:     "updated",
+				OnCreate: true,
+				OnUpdate: true,
+			},
+			&core.NumberField{
+				Name:     "telegram_id",
+				Required: true,
+				OnlyInt:  true,
+			},
+			// telegram format: { "id": 123, "username": "...", "first_name": "...", "last_name": "..." }
+			&core.JSONField{
+				Name:     "telegram",
+				Required: false,
+			},
+			&core.RelationField{
+				Name:          "existing_user",
+				Required:      true,
+				CollectionId:  users.Id,
+				MaxSelect:     1,
+				CascadeDelete: true,
+			},
+			&core.RelationField{
+				Name:          "requested_user",
+				Required:      true,
+				CollectionId:  users.Id,
+				MaxSelect:     1,
+				CascadeDelete: true,
+			},
+			&core.SelectField{
+				Name:     "status",
+				Required: true,
+				Values:   []string{"open", "resolved"},
+			},
+			&core.SelectField{
+				Name:     "resolution",
+				Required: false,
+				Values:   []string{"keep_existing", "transfer", "merge"},
+			},
+			&core.RelationField{
+				Name:         "resolved_by",
+				Required:     false,
+				CollectionId: users.Id,
+				MaxSelect:    1,
+			},
+			&core.DateField{
+				Name:     "resolved_at",
+				Required: false,
+			},
+		)
+
+		conflicts.AddIndex("idx_telegram_conflicts_status", false, "status", "")
+
+		return app.Save(conflicts)
+	}, func(app core.App) error {
+		conflicts, err := app.FindCollectionByNameOrId("telegram_conflicts")
+		if err != nil {
+			return err
+		}
+		return app.Delete(conflicts)
+	})
+}
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+var memberCommandMessages = map[string]any{
+	"help":         "Available commands:\n/groups - your groups and invite links\n/status - your request progress\n/profile - your profile\n/help - this message",
+	"groups":       "Your groups:\n\n{groups}",
+	"groups_empty": "You are not in any group yet.\n\n{url}",
+	"status":       "📋 Request: {status}\nGroup: {group}\nLeader approval: {leader_approved}\nAdmin confirmation: {admin_confirmed}",
+	"status_none":  "No request found for your account.\n\n{url}",
+	"status_labels": map[string]any{
+		"0-pending":  "Pending review",
+		"1-accepted": "Accepted",
+		"2-assigned": "Assigned to a group",
+		"3-approved": "Approved",
+		"9-rejected": "Rejected",
+	},
+	"profile": "👤 {name}\nEmail: {email}\nTelegram: {telegram}\nStatus: {user_status}\n\nEdit your profile:\n{url}",
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		record, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'bot_messages'",
+			map[string]any{},
+		)
+		if err != nil {
+			return err
+		}
+
+		data := map[string]any{}
+		if err := record.UnmarshalJSONField("data", &data); err != nil {
+			return err
+		}
+
+		for key, value := range memberCommandMessages {
+			if _, ok := data[key]; !ok {
+				data[key] = value
+			}
+		}
+
+		record.Set("data", data)
+		return app.Save(record)
+	}, func(app core.App) error {
+		record, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'bot_messages'",
+			map[string]any{},
+		)
+		if err != nil {
+			return nil
+		}
+
+		data := map[string]any{}
+		if err := record.UnmarshalJSONField("data", &data); err != nil {
+			return err
+		}
+
+		for key := range memberCommandMessages {
+			delete(data, key)
+		}
+
+		record.Set("data", data)
+		return app.Save(record)
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		guardians, err := app.FindCollectionByNameOrId("guardians")
+		if err != nil {
+			return err
+		}
+
+		guardians.Fields.Add(
+			&core.DateField{
+				Name:     "leader_notified_at",
+				Required: false,
+			},
+		)
+
+		return app.Save(guardians)
+	}, func(app core.App) error {
+		guardians, err := app.FindCollectionByNameOrId("guardians")
+		if err != nil {
+			return err
+		}
+
+		field := guardians.Fields.GetByName("leader_notified_at")
+		if field != nil {
+			guardians.Fields.RemoveById(field.GetId())
+		}
+
+		return app.Save(guardians)
+	})
+}
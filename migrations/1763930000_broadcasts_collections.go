@@ -0,0 +1,172 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		users.Fields.Add(
+			&core.BoolField{
+				Name:     "broadcast_opt_out",
+				Required: false,
+			},
+		)
+
+		if err := app.Save(users); err != nil {
+			return err
+		}
+
+		// Broadcasts are managed by admin users
+		broadcasts := core.NewBaseCollection("broadcasts")
+		broadcasts.ListRule = types.Pointer("@request.auth.admin = true")
+		broadcasts.ViewRule = types.Pointer("@request.auth.admin = true")
+		broadcasts.CreateRule = types.Pointer("@request.auth.admin = true")
+		broadcasts.UpdateRule = types.Pointer("@request.auth.admin = true && status = 'draft'")
+		broadcasts.DeleteRule = types.Pointer("@request.auth.admin = true && status = 'draft'")
+
+		broadcasts.Fields.Add(
+			&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.AutodateField{
+				Name:     "updated",
+				OnCreate: true,
+				OnUpdate: true,
+			},
+			&core.TextField{
+				Name:     "title",
+				Required: true,
+				Max:      200,
+			},
+			// body placeholders: {name}, {email}, {url}
+			&core.TextField{
+				Name:     "body",
+				Required: true,
+				Max:      4000,
+			},
+			// filter format: { "regions": [], "groups": [], "user_statuses": [], "request_statuses": [], "roles": ["member", "admin", "leader"] }
+			&core.JSONField{
+				Name:     "filter",
+				Required: false,
+			},
+			&core.SelectField{
+				Name:     "status",
+				Required: true,
+				Values:   []string{"draft", "queued", "sending", "done"},
+			},
+			&core.RelationField{
+				Name:         "created_by",
+				Required:     false,
+				CollectionId: users.Id,
+				MaxSelect:    1,
+			},
+			&core.DateField{
+				Name:     "started_at",
+				Required: false,
+			},
+			&core.DateField{
+				Name:     "finished_at",
+				Required: false,
+			},
+			&core.NumberField{
+				Name:    "sent_count",
+				OnlyInt: true,
+			},
+			&core.NumberField{
+				Name:    "failed_count",
+				OnlyInt: true,
+			},
+		)
+
+		if err := app.Save(broadcasts); err != nil {
+			return err
+		}
+
+		deliveries := core.NewBaseCollection("broadcast_deliveries")
+		deliveries.ListRule = types.Pointer("@request.auth.admin = true")
+		deliveries.ViewRule = types.Pointer("@request.auth.admin = true")
+		deliveries.CreateRule = nil
+		deliveries.UpdateRule = nil
+		deliveries.DeleteRule = nil
+
+		deliveries.Fields.Add(
+			&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.AutodateField{
+				Name:     "updated",
+				OnCreate: true,
+				OnUpdate: true,
+			},
+			&core.RelationField{
+				Name:          "broadcast",
+				Required:      true,
+				CollectionId:  broadcasts.Id,
+				MaxSelect:     1,
+				CascadeDelete: true,
+			},
+			&core.RelationField{
+				Name:          "user",
+				Required:      true,
+				CollectionId:  users.Id,
+				MaxSelect:     1,
+				CascadeDelete: true,
+			},
+			&core.SelectField{
+				Name:     "status",
+				Required: true,
+				Values:   []string{"pending", "sent", "failed", "skipped"},
+			},
+			&core.TextField{
+				Name:     "error",
+				Required: false,
+				Max:      1000,
+			},
+			&core.DateField{
+				Name:     "sent_at",
+				Required: false,
+			},
+		)
+
+		deliveries.AddIndex("idx_broadcast_deliveries_user", true, "broadcast, user", "")
+		deliveries.AddIndex("idx_broadcast_deliveries_status", false, "status", "")
+
+		return app.Save(deliveries)
+	}, func(app core.App) error {
+		deliveries, err := app.FindCollectionByNameOrId("broadcast_deliveries")
+		if err == nil {
+			if err := app.Delete(deliveries); err != nil {
+				return err
+			}
+		}
+
+		broadcasts, err := app.FindCollectionByNameOrId("broadcasts")
+		if err == nil {
+			if err := app.Delete(broadcasts); err != nil {
+				return err
+			}
+		}
+
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		field := users.Fields.GetByName("broadcast_opt_out")
+		if field != nil {
+			users.Fields.RemoveById(field.GetId())
+		}
+
+		return app.Save(users)
+	})
+}
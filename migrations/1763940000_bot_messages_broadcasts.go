@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const broadcastsHelpMessage = "Available commands:\n/groups - your groups and invite links\n/status - your request progress\n/profile - your profile\n/unsubscribe - stop announcements\n/subscribe - receive announcements again\n/help - this message"
+
+func init() {
+	m.Register(func(app core.App) error {
+		record, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'bot_messages'",
+			map[string]any{},
+		)
+		if err != nil {
+			return err
+		}
+
+		data := map[string]any{}
+		if err := record.UnmarshalJSONField("data", &data); err != nil {
+			return err
+		}
+
+		if _, ok := data["unsubscribed"]; !ok {
+			data["unsubscribed"] = "🔕 You will no longer receive announcements.\n\nSend /subscribe to receive them again."
+		}
+		if _, ok := data["subscribed"]; !ok {
+			data["subscribed"] = "🔔 You will receive announcements again."
+		}
+
+		// Only replace the help text if it was not customized
+		if data["help"] == memberCommandMessages["help"] {
+			data["help"] = broadcastsHelpMessage
+		}
+
+		record.Set("data", data)
+		return app.Save(record)
+	}, func(app core.App) error {
+		record, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'bot_messages'",
+			map[string]any{},
+		)
+		if err != nil {
+			return nil
+		}
+
+		data := map[string]any{}
+		if err := record.UnmarshalJSONField("data", &data); err != nil {
+			return err
+		}
+
+		delete(data, "unsubscribed")
+		delete(data, "subscribed")
+		if data["help"] == broadcastsHelpMessage {
+			data["help"] = memberCommandMessages["help"]
+		}
+
+		record.Set("data", data)
+		return app.Save(record)
+	})
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		groups.Fields.Add(
+			&core.TextField{
+				Name:     "username",
+				Required: false,
+				Max:      100,
+			},
+			&core.NumberField{
+				Name:    "member_count",
+				OnlyInt: true,
+			},
+			&core.FileField{
+				Name:      "photo",
+				Required:  false,
+				MaxSelect: 1,
+				MaxSize:   5 * 1024 * 1024,
+				MimeTypes: []string{"image/jpeg", "image/png", "image/webp"},
+			},
+			// Telegram file_unique_id of the synced photo, to skip unchanged downloads
+			&core.TextField{
+				Name:     "photo_id",
+				Required: false,
+				Hidden:   true,
+				Max:      200,
+			},
+		)
+
+		return app.Save(groups)
+	}, func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		for _, fieldName := range []string{"username", "member_count", "photo", "photo_id"} {
+			field := groups.Fields.GetByName(fieldName)
+			if field != nil {
+				groups.Fields.RemoveById(field.GetId())
+			}
+		}
+
+		return app.Save(groups)
+	})
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		// Set when the bot loses admin rights; the group keeps its data but is hidden.
+		groups.Fields.Add(
+			&core.DateField{
+				Name:     "disabled_at",
+				Required: false,
+			},
+		)
+
+		groups.ListRule = types.Pointer("disabled_at = '' || @request.auth.admin = true")
+		groups.ViewRule = types.Pointer("disabled_at = '' || @request.auth.admin = true")
+
+		return app.Save(groups)
+	}, func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		field := groups.Fields.GetByName("disabled_at")
+		if field != nil {
+			groups.Fields.RemoveById(field.GetId())
+		}
+
+		groups.ListRule = types.Pointer("")
+		groups.ViewRule = types.Pointer("")
+
+		return app.Save(groups)
+	})
+}
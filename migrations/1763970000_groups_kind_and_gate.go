@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		groups.Fields.Add(
+			&core.SelectField{
+				Name:     "kind",
+				Required: false,
+				Values:   []string{"group", "channel"},
+			},
+			// members_only: the bot removes unknown Telegram users and declines their join requests
+			&core.SelectField{
+				Name:     "gate_mode",
+				Required: false,
+				Values:   []string{"off", "members_only"},
+			},
+		)
+
+		if err := app.Save(groups); err != nil {
+			return err
+		}
+
+		existingGroups, err := app.FindRecordsByFilter("groups", "", "", 0, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, group := range existingGroups {
+			var telegramData struct {
+				Type string `json:"type"`
+			}
+			group.UnmarshalJSONField("telegram", &telegramData)
+
+			kind := "group"
+			if telegramData.Type == "channel" {
+				kind = "channel"
+			}
+
+			group.Set("kind", kind)
+			group.Set("gate_mode", "off")
+			if err := app.Save(group); err != nil {
+				return err
+			}
+		}
+
+		messagesRecord, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'bot_messages'",
+			map[string]any{},
+		)
+		if err != nil {
+			return err
+		}
+
+		data := map[string]any{}
+		if err := messagesRecord.UnmarshalJSONField("data", &data); err != nil {
+			return err
+		}
+
+		if _, ok := data["members_only"]; !ok {
+			data["members_only"] = "This chat is for registered members only.\n\nSign up or connect your Telegram account here:\n{url}"
+		}
+
+		messagesRecord.Set("data", data)
+		return app.Save(messagesRecord)
+	}, func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		for _, fieldName := range []string{"kind", "gate_mode"} {
+			field := groups.Fields.GetByName(fieldName)
+			if field != nil {
+				groups.Fields.RemoveById(field.GetId())
+			}
+		}
+
+		return app.Save(groups)
+	})
+}
@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		sightings := core.NewBaseCollection("telegram_sightings")
+		sightings.ListRule = nil
+		sightings.ViewRule = nil
+		sightings.CreateRule = nil
+		sightings.UpdateRule = nil
+		sightings.DeleteRule = nil
+
+		sightings.Fields.Add(
+			&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.AutodateField{
+				Name:     "updated",
+				OnCreate: true,
+				OnUpdate: true,
+			},
+			&core.NumberField{
+				Name:     "telegram_id",
+				Required: true,
+				OnlyInt:  true,
+			},
+			&core.TextField{
+				Name:     "username",
+				Required: false,
+			},
+			&core.TextField{
+				Name:     "first_name",
+				Required: false,
+			},
+			&core.TextField{
+				Name:     "last_name",
+				Required: false,
+			},
+			&core.RelationField{
+				Name:          "group",
+				Required:      true,
+				CollectionId:  groups.Id,
+				MaxSelect:     1,
+				CascadeDelete: true,
+			},
+			&core.TextField{
+				Name:     "chat_id",
+				Required: true,
+			},
+			// Telegram chat member status: member, restricted, administrator, creator, left, kicked
+			&core.TextField{
+				Name:     "status",
+				Required: true,
+			},
+			&core.DateField{
+				Name:     "seen_at",
+				Required: true,
+			},
+		)
+
+		sightings.AddIndex("idx_telegram_sightings_identity", true, "telegram_id, `group`", "")
+
+		return app.Save(sightings)
+	}, func(app core.App) error {
+		sightings, err := app.FindCollectionByNameOrId("telegram_sightings")
+		if err != nil {
+			return err
+		}
+		return app.Delete(sightings)
+	})
+}
@@ -0,0 +1,120 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		// restrict: unknown Telegram users are read-only until they link an account
+		if field, ok := groups.Fields.GetByName("gate_mode").(*core.SelectField); ok {
+			field.Values = []string{"off", "members_only", "restrict"}
+		}
+
+		groups.Fields.Add(
+			// Minutes before a restricted user is removed, 0 keeps them read-only
+			&core.NumberField{
+				Name:     "gate_timeout",
+				Required: false,
+				OnlyInt:  true,
+			},
+		)
+
+		if err := app.Save(groups); err != nil {
+			return err
+		}
+
+		sightings, err := app.FindCollectionByNameOrId("telegram_sightings")
+		if err != nil {
+			return err
+		}
+
+		sightings.Fields.Add(
+			&core.DateField{
+				Name:     "restricted_at",
+				Required: false,
+			},
+		)
+
+		if err := app.Save(sightings); err != nil {
+			return err
+		}
+
+		existingGroups, err := app.FindRecordsByFilter("groups", "", "", 0, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, group := range existingGroups {
+			group.Set("gate_timeout", 1440)
+			if err := app.Save(group); err != nil {
+				return err
+			}
+		}
+
+		messagesRecord, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'bot_messages'",
+			map[string]any{},
+		)
+		if err != nil {
+			return err
+		}
+
+		data := map[string]any{}
+		if err := messagesRecord.UnmarshalJSONField("data", &data); err != nil {
+			return err
+		}
+
+		if _, ok := data["gate_restricted"]; !ok {
+			data["gate_restricted"] = "👋 Welcome, {name}!\n\nThis group is for registered members. You can read along, but to write here please sign up or connect your Telegram account:\n{url}"
+		}
+
+		messagesRecord.Set("data", data)
+		return app.Save(messagesRecord)
+	}, func(app core.App) error {
+		groups, err := app.FindCollectionByNameOrId("groups")
+		if err != nil {
+			return err
+		}
+
+		restricted, err := app.FindRecordsByFilter("groups", "gate_mode = 'restrict'", "", 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, group := range restricted {
+			group.Set("gate_mode", "off")
+			if err := app.Save(group); err != nil {
+				return err
+			}
+		}
+
+		if field, ok := groups.Fields.GetByName("gate_mode").(*core.SelectField); ok {
+			field.Values = []string{"off", "members_only"}
+		}
+		if field := groups.Fields.GetByName("gate_timeout"); field != nil {
+			groups.Fields.RemoveById(field.GetId())
+		}
+
+		if err := app.Save(groups); err != nil {
+			return err
+		}
+
+		sightings, err := app.FindCollectionByNameOrId("telegram_sightings")
+		if err != nil {
+			return err
+		}
+
+		if field := sightings.Fields.GetByName("restricted_at"); field != nil {
+			sightings.Fields.RemoveById(field.GetId())
+		}
+
+		return app.Save(sightings)
+	})
+}
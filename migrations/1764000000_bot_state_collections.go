@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		state := core.NewBaseCollection("bot_state")
+		state.ListRule = nil
+		state.ViewRule = nil
+		state.CreateRule = nil
+		state.UpdateRule = nil
+		state.DeleteRule = nil
+
+		state.Fields.Add(
+			&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.AutodateField{
+				Name:     "updated",
+				OnCreate: true,
+				OnUpdate: true,
+			},
+			&core.TextField{
+				Name:     "name",
+				Required: true,
+			},
+			// updates format: { "offset": 123 }
+			&core.JSONField{
+				Name:     "data",
+				Required: false,
+			},
+		)
+
+		state.AddIndex("idx_bot_state_name", true, "name", "")
+
+		if err := app.Save(state); err != nil {
+			return err
+		}
+
+		updates := core.NewBaseCollection("bot_updates")
+		updates.ListRule = nil
+		updates.ViewRule = nil
+		updates.CreateRule = nil
+		updates.UpdateRule = nil
+		updates.DeleteRule = nil
+
+		updates.Fields.Add(
+			&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.NumberField{
+				Name:     "update_id",
+				Required: true,
+				OnlyInt:  true,
+			},
+		)
+
+		updates.AddIndex("idx_bot_updates_update_id", true, "update_id", "")
+		updates.AddIndex("idx_bot_updates_created", false, "created", "")
+
+		return app.Save(updates)
+	}, func(app core.App) error {
+		for _, name := range []string{"bot_updates", "bot_state"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
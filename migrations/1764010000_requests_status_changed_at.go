@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		requests, err := app.FindCollectionByNameOrId("requests")
+		if err != nil {
+			return err
+		}
+
+		requests.Fields.Add(
+			&core.DateField{
+				Name:     "status_changed_at",
+				Required: false,
+			},
+		)
+
+		if err := app.Save(requests); err != nil {
+			return err
+		}
+
+		existingRequests, err := app.FindRecordsByFilter("requests", "", "", 0, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, request := range existingRequests {
+			request.Set("status_changed_at", request.GetDateTime("updated"))
+			if err := app.Save(request); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		requests, err := app.FindCollectionByNameOrId("requests")
+		if err != nil {
+			return err
+		}
+
+		if field := requests.Fields.GetByName("status_changed_at"); field != nil {
+			requests.Fields.RemoveById(field.GetId())
+		}
+
+		return app.Save(requests)
+	})
+}
@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		settings, err := app.FindCollectionByNameOrId("settings")
+		if err != nil {
+			return err
+		}
+
+		// Rules are keyed by request status or guardian step.
+		// escalate_after_hours 0 disables the escalation to admins.
+		slaConfig := map[string]any{
+			"repeat_hours": 24,
+			"rules": map[string]any{
+				"1-accepted": map[string]any{
+					"remind_after_hours":   48,
+					"escalate_after_hours": 120,
+				},
+				"2-assigned": map[string]any{
+					"remind_after_hours":   72,
+					"escalate_after_hours": 168,
+				},
+				"leader_approval": map[string]any{
+					"remind_after_hours":   48,
+					"escalate_after_hours": 120,
+				},
+				"admin_confirmation": map[string]any{
+					"remind_after_hours":   48,
+					"escalate_after_hours": 0,
+				},
+			},
+		}
+
+		existingRecord, _ := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'sla'",
+			map[string]any{},
+		)
+		if existingRecord == nil {
+			slaRecord := core.NewRecord(settings)
+			slaRecord.Set("name", "sla")
+			slaRecord.Set("data", slaConfig)
+			if err := app.Save(slaRecord); err != nil {
+				return err
+			}
+		}
+
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		reminders := core.NewBaseCollection("reminders")
+		reminders.ListRule = nil
+		reminders.ViewRule = nil
+		reminders.CreateRule = nil
+		reminders.UpdateRule = nil
+		reminders.DeleteRule = nil
+
+		reminders.Fields.Add(
+			&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.SelectField{
+				Name:     "target_collection",
+				Required: true,
+				Values:   []string{"requests", "guardians"},
+			},
+			&core.TextField{
+				Name:     "target",
+				Required: true,
+			},
+			// request status or guardian step, see the sla setting
+			&core.TextField{
+				Name:     "rule",
+				Required: true,
+			},
+			&core.SelectField{
+				Name:     "stage",
+				Required: true,
+				Values:   []string{"remind", "escalate"},
+			},
+			&core.RelationField{
+				Name:          "recipient",
+				Required:      true,
+				CollectionId:  users.Id,
+				MaxSelect:     1,
+				CascadeDelete: true,
+			},
+			&core.SelectField{
+				Name:     "channel",
+				Required: true,
+				Values:   []string{"telegram", "email"},
+			},
+			&core.DateField{
+				Name:     "sent_at",
+				Required: true,
+			},
+		)
+
+		reminders.AddIndex("idx_reminders_target", false, "target, rule, stage, recipient", "")
+
+		return app.Save(reminders)
+	}, func(app core.App) error {
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return err
+		}
+		if err := app.Delete(reminders); err != nil {
+			return err
+		}
+
+		slaRecord, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'sla'",
+			map[string]any{},
+		)
+		if err == nil && slaRecord != nil {
+			return app.Delete(slaRecord)
+		}
+
+		return nil
+	})
+}
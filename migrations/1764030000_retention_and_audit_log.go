@@ -0,0 +1,144 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		settings, err := app.FindCollectionByNameOrId("settings")
+		if err != nil {
+			return err
+		}
+
+		// action: anonymize | delete. The age counts from the last status change.
+		// unverified_only limits a rule to requests without a verified user account.
+		// While dry_run is true the job only reports what it would do.
+		retentionConfig := map[string]any{
+			"dry_run": true,
+			"rules": []map[string]any{
+				{
+					"id":         "rejected_anonymize",
+					"status":     "9-rejected",
+					"action":     "anonymize",
+					"after_days": 180,
+				},
+				{
+					"id":              "pending_unverified_delete",
+					"status":          "0-pending",
+					"action":          "delete",
+					"after_days":      365,
+					"unverified_only": true,
+				},
+			},
+		}
+
+		existingRecord, _ := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'retention'",
+			map[string]any{},
+		)
+		if existingRecord == nil {
+			retentionRecord := core.NewRecord(settings)
+			retentionRecord.Set("name", "retention")
+			retentionRecord.Set("data", retentionConfig)
+			if err := app.Save(retentionRecord); err != nil {
+				return err
+			}
+		}
+
+		requests, err := app.FindCollectionByNameOrId("requests")
+		if err != nil {
+			return err
+		}
+
+		requests.Fields.Add(
+			&core.DateField{
+				Name:     "anonymized_at",
+				Required: false,
+			},
+		)
+
+		if err := app.Save(requests); err != nil {
+			return err
+		}
+
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		auditLog := core.NewBaseCollection("audit_log")
+		auditLog.ListRule = types.Pointer("@request.auth.admin = true")
+		auditLog.ViewRule = types.Pointer("@request.auth.admin = true")
+		auditLog.CreateRule = nil
+		auditLog.UpdateRule = nil
+		auditLog.DeleteRule = nil
+
+		auditLog.Fields.Add(
+			&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.TextField{
+				Name:     "action",
+				Required: true,
+			},
+			// Empty for scheduled jobs
+			&core.RelationField{
+				Name:         "actor",
+				Required:     false,
+				CollectionId: users.Id,
+				MaxSelect:    1,
+			},
+			&core.TextField{
+				Name:     "target_collection",
+				Required: false,
+			},
+			&core.TextField{
+				Name:     "target",
+				Required: false,
+			},
+			&core.JSONField{
+				Name:     "details",
+				Required: false,
+			},
+		)
+
+		auditLog.AddIndex("idx_audit_log_action", false, "action, created", "")
+
+		return app.Save(auditLog)
+	}, func(app core.App) error {
+		auditLog, err := app.FindCollectionByNameOrId("audit_log")
+		if err != nil {
+			return err
+		}
+		if err := app.Delete(auditLog); err != nil {
+			return err
+		}
+
+		requests, err := app.FindCollectionByNameOrId("requests")
+		if err != nil {
+			return err
+		}
+		if field := requests.Fields.GetByName("anonymized_at"); field != nil {
+			requests.Fields.RemoveById(field.GetId())
+		}
+		if err := app.Save(requests); err != nil {
+			return err
+		}
+
+		retentionRecord, err := app.FindFirstRecordByFilter(
+			"settings",
+			"name = 'retention'",
+			map[string]any{},
+		)
+		if err == nil && retentionRecord != nil {
+			return app.Delete(retentionRecord)
+		}
+
+		return nil
+	})
+}
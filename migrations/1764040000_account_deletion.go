@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		guardians, err := app.FindCollectionByNameOrId("guardians")
+		if err != nil {
+			return err
+		}
+
+		// A guardianship stays without guardian when the guardian deleted
+		// their account and nobody could take over.
+		if field, ok := guardians.Fields.GetByName("guardian").(*core.RelationField); ok {
+			field.Required = false
+		}
+
+		guardians.Fields.Add(
+			&core.DateField{
+				Name:     "guardian_removed_at",
+				Required: false,
+			},
+		)
+
+		return app.Save(guardians)
+	}, func(app core.App) error {
+		guardians, err := app.FindCollectionByNameOrId("guardians")
+		if err != nil {
+			return err
+		}
+
+		if field := guardians.Fields.GetByName("guardian_removed_at"); field != nil {
+			guardians.Fields.RemoveById(field.GetId())
+		}
+
+		if field, ok := guardians.Fields.GetByName("guardian").(*core.RelationField); ok {
+			field.Required = true
+		}
+
+		return app.Save(guardians)
+	})
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		auditLog, err := app.FindCollectionByNameOrId("audit_log")
+		if err != nil {
+			return err
+		}
+
+		// The actor relation is cleared when the user is deleted,
+		// actor_id keeps who it was.
+		auditLog.Fields.Add(
+			&core.TextField{
+				Name:     "actor_id",
+				Required: false,
+			},
+		)
+
+		if err := app.Save(auditLog); err != nil {
+			return err
+		}
+
+		records, err := app.FindRecordsByFilter("audit_log", "actor != ''", "", 0, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			record.Set("actor_id", record.GetString("actor"))
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		auditLog, err := app.FindCollectionByNameOrId("audit_log")
+		if err != nil {
+			return err
+		}
+
+		if field := auditLog.Fields.GetByName("actor_id"); field != nil {
+			auditLog.Fields.RemoveById(field.GetId())
+		}
+
+		return app.Save(auditLog)
+	})
+}
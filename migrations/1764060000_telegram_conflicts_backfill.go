@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Telegram IDs linked to more than one user before the uniqueness rule
+		var duplicates []struct {
+			TelegramID int64 `db:"telegram_id"`
+		}
+		err := app.DB().NewQuery(
+			"SELECT json_extract([[telegram]], '$.id') AS [[telegram_id]] FROM {{users}} " +
+				"WHERE json_valid([[telegram]]) AND COALESCE(json_extract([[telegram]], '$.id'), 0) != 0 " +
+				"GROUP BY [[telegram_id]] HAVING COUNT(*) > 1",
+		).All(&duplicates)
+		if err != nil {
+			return err
+		}
+
+		conflicts, err := app.FindCollectionByNameOrId("telegram_conflicts")
+		if err != nil {
+			return err
+		}
+
+		for _, duplicate := range duplicates {
+			users, err := app.FindRecordsByFilter(
+				"users",
+				"telegram.id = {:id}",
+				"created",
+				0,
+				0,
+				map[string]any{"id": duplicate.TelegramID},
+			)
+			if err != nil {
+				return err
+			}
+
+			// The oldest link is kept as the existing user until an admin decides
+			for _, requested := range users[1:] {
+				existing, _ := app.FindFirstRecordByFilter(
+					"telegram_conflicts",
+					"telegram_id = {:id} && existing_user = {:existing} && requested_user = {:requested} && status = 'open'",
+					map[string]any{
+						"id":        duplicate.TelegramID,
+						"existing":  users[0].Id,
+						"requested": requested.Id,
+					},
+				)
+				if existing != nil {
+					continue
+				}
+
+				conflict := core.NewRecord(conflicts)
+				conflict.Set("telegram_id", duplicate.TelegramID)
+				conflict.Set("telegram", requested.Get("telegram"))
+				conflict.Set("existing_user", users[0].Id)
+				conflict.Set("requested_user", requested.Id)
+				conflict.Set("status", "open")
+				if err := app.Save(conflict); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		return nil
+	})
+}
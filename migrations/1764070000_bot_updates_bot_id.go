@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		updates, err := app.FindCollectionByNameOrId("bot_updates")
+		if err != nil {
+			return err
+		}
+
+		// Update IDs are per bot, a new token must not match the old IDs
+		updates.Fields.Add(
+			&core.NumberField{
+				Name:     "bot_id",
+				Required: false,
+				OnlyInt:  true,
+			},
+		)
+		updates.RemoveIndex("idx_bot_updates_update_id")
+		updates.AddIndex("idx_bot_updates_update_id", true, "bot_id, update_id", "")
+
+		if err := app.Save(updates); err != nil {
+			return err
+		}
+
+		legacy, err := app.FindFirstRecordByFilter("bot_state", "name = 'updates'", map[string]any{})
+		if err != nil {
+			return nil
+		}
+
+		// The bot ID is the part of the token before the colon
+		botID := int64(0)
+		if settings, err := app.FindFirstRecordByFilter("settings", "name = 'telegram'", map[string]any{}); err == nil {
+			var telegramData struct {
+				Token string `json:"token"`
+			}
+			if err := settings.UnmarshalJSONField("data", &telegramData); err == nil {
+				prefix, _, _ := strings.Cut(telegramData.Token, ":")
+				botID, _ = strconv.ParseInt(prefix, 10, 64)
+			}
+		}
+
+		// Without a bot the offset belongs to nobody
+		if botID == 0 {
+			return app.Delete(legacy)
+		}
+
+		records, err := app.FindRecordsByFilter("bot_updates", "bot_id = 0", "", 0, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			record.Set("bot_id", botID)
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+
+		legacy.Set("name", fmt.Sprintf("updates:%d", botID))
+		return app.Save(legacy)
+	}, func(app core.App) error {
+		// Handled IDs are kept for a day only, they are dropped with the field
+		records, err := app.FindRecordsByFilter("bot_updates", "bot_id != 0", "", 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := app.Delete(record); err != nil {
+				return err
+			}
+		}
+
+		states, err := app.FindRecordsByFilter("bot_state", "name ~ 'updates:%'", "", 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			if err := app.Delete(state); err != nil {
+				return err
+			}
+		}
+
+		updates, err := app.FindCollectionByNameOrId("bot_updates")
+		if err != nil {
+			return err
+		}
+
+		updates.RemoveIndex("idx_bot_updates_update_id")
+		updates.AddIndex("idx_bot_updates_update_id", true, "update_id", "")
+		if field := updates.Fields.GetByName("bot_id"); field != nil {
+			updates.Fields.RemoveById(field.GetId())
+		}
+
+		return app.Save(updates)
+	})
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		sightings, err := app.FindCollectionByNameOrId("telegram_sightings")
+		if err != nil {
+			return err
+		}
+
+		// The chat comes from the group, a copy goes stale when the group
+		// migrates to a supergroup.
+		if field := sightings.Fields.GetByName("chat_id"); field != nil {
+			sightings.Fields.RemoveById(field.GetId())
+		}
+
+		return app.Save(sightings)
+	}, func(app core.App) error {
+		sightings, err := app.FindCollectionByNameOrId("telegram_sightings")
+		if err != nil {
+			return err
+		}
+
+		sightings.Fields.Add(
+			&core.TextField{
+				Name:     "chat_id",
+				Required: false,
+			},
+		)
+
+		return app.Save(sightings)
+	})
+}
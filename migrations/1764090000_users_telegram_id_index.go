@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Users linked to the same Telegram ID before the uniqueness rule
+		// have open conflicts. The index is then added once the last one is
+		// resolved (see ensureTelegramIDIndex in the bot package).
+		var duplicates []struct {
+			TelegramID int64 `db:"telegram_id"`
+		}
+		err := app.DB().NewQuery(
+			"SELECT json_extract([[telegram]], '$.id') AS [[telegram_id]] FROM {{users}} " +
+				"WHERE json_valid([[telegram]]) AND json_extract([[telegram]], '$.id') IS NOT NULL " +
+				"GROUP BY [[telegram_id]] HAVING COUNT(*) > 1",
+		).All(&duplicates)
+		if err != nil {
+			return err
+		}
+		if len(duplicates) > 0 {
+			return nil
+		}
+
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		users.AddIndex(
+			"idx_users_telegram_id",
+			true,
+			// NULL for unlinked users, NULLs do not collide
+			"(CASE WHEN json_valid(`telegram`) THEN json_extract(`telegram`, '$.id') END)",
+			"",
+		)
+
+		return app.Save(users)
+	}, func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		users.RemoveIndex("idx_users_telegram_id")
+
+		return app.Save(users)
+	})
+}
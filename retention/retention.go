@@ -0,0 +1,224 @@
+// Package retention applies the data retention rules of the retention
+// setting to applicant requests.
+package retention
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/audit"
+)
+
+const (
+	ActionAnonymize = "anonymize"
+	ActionDelete    = "delete"
+)
+
+// Rule selects requests by status and age and says what to do with them.
+type Rule struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	Action         string `json:"action"`
+	AfterDays      int    `json:"after_days"`
+	UnverifiedOnly bool   `json:"unverified_only"`
+}
+
+// Settings is the data of the retention setting.
+type Settings struct {
+	DryRun bool   `json:"dry_run"`
+	Rules  []Rule `json:"rules"`
+}
+
+// Result reports what a rule matched and how many requests it changed.
+type Result struct {
+	Rule     string   `json:"rule"`
+	Action   string   `json:"action"`
+	Matched  int      `json:"matched"`
+	Applied  int      `json:"applied"`
+	Requests []string `json:"requests"`
+}
+
+// LoadSettings reads the retention setting.
+func LoadSettings(app core.App) (Settings, error) {
+	var settings Settings
+
+	record, err := app.FindFirstRecordByFilter(
+		"settings",
+		"name = 'retention'",
+		map[string]any{},
+	)
+	if err != nil {
+		return settings, fmt.Errorf("retention settings not found: %w", err)
+	}
+
+	if err := record.UnmarshalJSONField("data", &settings); err != nil {
+		return settings, fmt.Errorf("failed to parse retention settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// Matches returns the requests the rule applies to now.
+func Matches(app core.App, rule Rule) ([]*core.Record, error) {
+	if rule.Status == "" || rule.AfterDays <= 0 {
+		return nil, fmt.Errorf("rule %q needs a status and after_days", rule.ID)
+	}
+
+	cutoff := types.NowDateTime().Add(-time.Duration(rule.AfterDays) * 24 * time.Hour)
+
+	records, err := app.FindRecordsByFilter(
+		"requests",
+		"status = {:status} && anonymized_at = '' && ((status_changed_at != '' && status_changed_at < {:cutoff}) || (status_changed_at = '' && updated < {:cutoff}))",
+		"",
+		0,
+		0,
+		map[string]any{
+			"status": rule.Status,
+			"cutoff": cutoff,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rule.UnverifiedOnly {
+		return records, nil
+	}
+
+	unverified := []*core.Record{}
+	for _, record := range records {
+		user, _ := app.FindFirstRecordByFilter(
+			"users",
+			"email = {:email} && verified = true",
+			map[string]any{"email": record.GetString("email")},
+		)
+		if user == nil {
+			unverified = append(unverified, record)
+		}
+	}
+
+	return unverified, nil
+}
+
+// AnonymizeRequest replaces the personal data of a request with placeholders.
+// Status, region and group stay for statistics.
+func AnonymizeRequest(app core.App, record *core.Record) error {
+	record.Set("name", "Anonymized")
+	record.Set("email", fmt.Sprintf("anonymized-%s@example.invalid", record.Id))
+	record.Set("motivation", "-")
+	record.Set("birth_year", "0")
+	record.Set("civil_status", "other")
+	record.Set("anonymized_at", types.NowDateTime())
+
+	return app.Save(record)
+}
+
+// Report returns what the rules would change now. It changes nothing and
+// leaves no audit entry, so it can back a read-only view.
+func Report(app core.App) ([]Result, error) {
+	return evaluate(app, false)
+}
+
+// Run evaluates every rule. With dryRun it only reports the matches.
+// Each run leaves one audit entry with the results.
+func Run(app core.App, dryRun bool, actor string) ([]Result, error) {
+	results, err := evaluate(app, !dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	action := "retention.apply"
+	if dryRun {
+		action = "retention.dry_run"
+	}
+
+	details := map[string]any{"results": results}
+	if err := audit.Log(app, audit.Entry{
+		Action:           action,
+		Actor:            actor,
+		TargetCollection: "requests",
+		Details:          details,
+	}); err != nil {
+		log.Printf("retention: %v", err)
+	}
+
+	return results, nil
+}
+
+// evaluate matches every rule and, with apply, anonymizes or deletes the matches.
+func evaluate(app core.App, apply bool) ([]Result, error) {
+	settings, err := LoadSettings(app)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, rule := range settings.Rules {
+		records, err := Matches(app, rule)
+		if err != nil {
+			log.Printf("retention: %v", err)
+			continue
+		}
+
+		result := Result{
+			Rule:     rule.ID,
+			Action:   rule.Action,
+			Matched:  len(records),
+			Requests: make([]string, 0, len(records)),
+		}
+
+		for _, record := range records {
+			result.Requests = append(result.Requests, record.Id)
+			if !apply {
+				continue
+			}
+
+			switch rule.Action {
+			case ActionAnonymize:
+				err = AnonymizeRequest(app, record)
+			case ActionDelete:
+				err = app.Delete(record)
+			default:
+				err = fmt.Errorf("unknown action %q", rule.Action)
+			}
+			if err != nil {
+				log.Printf("retention: rule %s failed for request %s: %v", rule.ID, record.Id, err)
+				continue
+			}
+			result.Applied++
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// BindJob registers the daily cron job that applies the retention rules.
+// While the setting has dry_run enabled, the job only records a report.
+func BindJob(app core.App) {
+	app.Cron().MustAdd("retention", "30 3 * * *", func() {
+		settings, err := LoadSettings(app)
+		if err != nil {
+			log.Printf("retention: %v", err)
+			return
+		}
+
+		results, err := Run(app, settings.DryRun, "")
+		if err != nil {
+			log.Printf("retention: %v", err)
+			return
+		}
+
+		summary := make([]string, 0, len(results))
+		for _, result := range results {
+			summary = append(summary, fmt.Sprintf("%s=%d/%d", result.Rule, result.Applied, result.Matched))
+		}
+		log.Printf("retention: run finished (dry_run=%v %s)", settings.DryRun, strings.Join(summary, " "))
+	})
+}
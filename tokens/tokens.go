@@ -0,0 +1,203 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"members/metrics"
+)
+
+// ServiceTelegramConnect is used by the dashboard to link a Telegram account.
+const ServiceTelegramConnect = "telegram_connect"
+
+// ServiceAccountDelete confirms a self-service account deletion by email.
+const ServiceAccountDelete = "account_delete"
+
+// ErrInvalidToken is returned when a token does not exist, is expired or was already used.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+var (
+	issuedTotal   = metrics.NewCounter("members_tokens_issued_total", "Tokens issued.", "service")
+	consumedTotal = metrics.NewCounter("members_tokens_consumed_total", "Token consume attempts.", "service", "result")
+)
+
+// IssueOptions holds the optional fields of a new token.
+type IssueOptions struct {
+	Group string
+	Meta  map[string]any
+	// Keep leaves previous tokens of the same user/service valid.
+	Keep bool
+}
+
+// Issue creates a new random token for the user and service.
+// Unless opts.Keep is set, previous tokens for the same user/service are revoked.
+func Issue(app core.App, userID string, service string, ttl time.Duration, opts IssueOptions) (*core.Record, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	collection, err := app.FindCollectionByNameOrId("tokens")
+	if err != nil {
+		return nil, fmt.Errorf("tokens collection not found: %w", err)
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("token", hex.EncodeToString(bytes))
+	record.Set("user", userID)
+	record.Set("service", service)
+	record.Set("expires_at", types.NowDateTime().Add(ttl))
+	if opts.Group != "" {
+		record.Set("group", opts.Group)
+	}
+	if opts.Meta != nil {
+		record.Set("meta", opts.Meta)
+	}
+
+	err = app.RunInTransaction(func(txApp core.App) error {
+		if !opts.Keep {
+			if err := Revoke(txApp, userID, service); err != nil {
+				return err
+			}
+		}
+		return txApp.Save(record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save token: %w", err)
+	}
+
+	issuedTotal.Inc(service)
+
+	return record, nil
+}
+
+// Consume marks a valid token as used and returns it.
+// The lookup and the update run in one transaction, so a token can be consumed only once.
+func Consume(app core.App, token string, service string) (*core.Record, error) {
+	var consumed *core.Record
+
+	err := app.RunInTransaction(func(txApp core.App) error {
+		record, err := Find(txApp, token, service)
+		if err != nil {
+			return err
+		}
+
+		record.Set("used_at", types.NowDateTime())
+		if err := txApp.Save(record); err != nil {
+			return err
+		}
+
+		consumed = record
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			consumedTotal.Inc(service, "invalid")
+		} else {
+			consumedTotal.Inc(service, "error")
+		}
+		return nil, err
+	}
+
+	consumedTotal.Inc(service, "ok")
+
+	return consumed, nil
+}
+
+// Find returns a valid token without using it up.
+func Find(app core.App, token string, service string) (*core.Record, error) {
+	record, err := app.FindFirstRecordByFilter(
+		"tokens",
+		"token = {:token} && service = {:service} && used_at = '' && expires_at > {:now}",
+		map[string]any{
+			"token":   token,
+			"service": service,
+			"now":     types.NowDateTime(),
+		},
+	)
+	if err != nil || record == nil {
+		return nil, ErrInvalidToken
+	}
+
+	return record, nil
+}
+
+// Revoke deletes all tokens of the user for the service.
+func Revoke(app core.App, userID string, service string) error {
+	records, err := List(app, userID, service)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := app.Delete(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List returns all tokens of the user for the service, newest first.
+func List(app core.App, userID string, service string) ([]*core.Record, error) {
+	return app.FindRecordsByFilter(
+		"tokens",
+		"user = {:user} && service = {:service}",
+		"-created",
+		0,
+		0,
+		map[string]any{
+			"user":    userID,
+			"service": service,
+		},
+	)
+}
+
+// CleanupExpired removes expired tokens and returns how many were deleted.
+func CleanupExpired(app core.App) (int, error) {
+	records, err := app.FindRecordsByFilter(
+		"tokens",
+		"expires_at < {:now}",
+		"-expires_at",
+		0,
+		0,
+		map[string]any{
+			"now": types.NowDateTime(),
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, record := range records {
+		if err := app.Delete(record); err != nil {
+			log.Printf("tokens: failed to delete expired token %s: %v", record.Id, err)
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// BindCleanupJob registers an hourly cron job that removes expired tokens.
+func BindCleanupJob(app core.App) {
+	app.Cron().MustAdd("tokens_cleanup", "0 * * * *", func() {
+		deleted, err := CleanupExpired(app)
+		if err != nil {
+			log.Printf("tokens: cleanup failed: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("tokens: removed %d expired tokens", deleted)
+		}
+	})
+}